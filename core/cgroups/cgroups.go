@@ -0,0 +1,145 @@
+// Package cgroups reads the resource usage of groups of processes from the
+// cgroup v2 filesystem. The root of the filesystem can be changed, so it can
+// read from a hybrid mount such as /sys/fs/cgroup/unified or a directory of
+// fixtures
+package cgroups
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// DefaultRoot is where the cgroup v2 filesystem is usually mounted
+const DefaultRoot = "/sys/fs/cgroup"
+
+// ErrNoUnifiedCgroup is returned when a process is not in a cgroup v2 group
+var ErrNoUnifiedCgroup = errors.New("process is not in a cgroup v2 group")
+
+// Max is the value of a limit that has no limit
+const Max int64 = -1
+
+// FS is a cgroup v2 filesystem mounted at a root directory
+type FS struct {
+	root string
+}
+
+// Default is the cgroup v2 filesystem mounted at DefaultRoot
+var Default = NewFS(DefaultRoot)
+
+// NewFS creates a new FS for the cgroup v2 filesystem mounted at root
+func NewFS(root string) FS {
+	return FS{root: root}
+}
+
+// Root gets the directory the filesystem is mounted at
+func (fs FS) Root() string {
+	return fs.root
+}
+
+// Group gets a cgroup by its path from the root of the hierarchy, such as
+// /system.slice/nginx.service. The group may not exist
+func (fs FS) Group(groupPath string) Group {
+	return Group{Path: path.Clean("/" + groupPath), fs: fs}
+}
+
+// GroupOf gets the cgroup that a process is in
+func (fs FS) GroupOf(proc procfs.Proc) (Group, error) {
+	var groupPath, err = proc.UnifiedCgroup()
+	if err != nil {
+		return Group{}, err
+	}
+	if len(groupPath) == 0 {
+		return Group{}, ErrNoUnifiedCgroup
+	}
+	return fs.Group(groupPath), nil
+}
+
+// Group is a cgroup in a cgroup v2 filesystem
+type Group struct {
+	Path string
+	fs   FS
+}
+
+// Exists returns true if the group exists
+func (group Group) Exists() bool {
+	var info, err = os.Stat(group.dir())
+	return err == nil && info.IsDir()
+}
+
+// Parent gets the group above this one, or the root group if this is the root
+func (group Group) Parent() Group {
+	return group.fs.Group(path.Dir(group.Path))
+}
+
+// IsRoot returns true if this is the root group
+func (group Group) IsRoot() bool {
+	return group.Path == "/"
+}
+
+// dir gets the directory of the group
+func (group Group) dir() string {
+	return filepath.Join(group.fs.root, filepath.FromSlash(group.Path))
+}
+
+// file gets the path of a file in the group
+func (group Group) file(name string) string {
+	return filepath.Join(group.dir(), name)
+}
+
+// readString reads a file in the group as a string
+func (group Group) readString(name string) (string, error) {
+	var content, err = ioutil.ReadFile(group.file(name))
+	return string(content), err
+}
+
+// readValue reads a file in the group that has a single number, or "max"
+func (group Group) readValue(name string) (int64, error) {
+	var content, err = group.readString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var value = strings.TrimSpace(content)
+	if value == "max" {
+		return Max, nil
+	}
+
+	var number, parseErr = strconv.ParseInt(value, 10, 64)
+	if parseErr != nil {
+		return 0, &procfs.ParseError{File: group.file(name),
+			Msg: "invalid number " + strconv.Quote(value)}
+	}
+	return number, nil
+}
+
+// readKeyedValues reads a file in the group that has a "key value" pair on
+// each line
+func (group Group) readKeyedValues(name string) (map[string]uint64, error) {
+	var content, err = group.readString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var values = make(map[string]uint64)
+	for _, line := range strings.Split(content, "\n") {
+		var fields = strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		var value, parseErr = strconv.ParseUint(fields[1], 10, 64)
+		if parseErr != nil {
+			return nil, &procfs.ParseError{File: group.file(name),
+				Msg: "invalid number " + strconv.Quote(fields[1])}
+		}
+		values[fields[0]] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,176 @@
+package cgroups
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// fixture creates a cgroup filesystem containing the files
+func fixture(t *testing.T, files map[string]string) FS {
+	var root = t.TempDir()
+	for name, content := range files {
+		var path = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewFS(root)
+}
+
+func TestGroupOf(t *testing.T) {
+	var procFS = procfs.NewFS(t.TempDir())
+	for pid, content := range map[string]string{
+		"10": "0::/system.slice/nginx.service\n",
+		"11": "4:memory:/docker/abc\n",
+	} {
+		if err := os.MkdirAll(procFS.Path(pid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(procFS.Path(pid, "cgroup"), []byte(content),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var fs = fixture(t, map[string]string{
+		"system.slice/nginx.service/cgroup.procs": "10\n",
+	})
+
+	var group, err = fs.GroupOf(procFS.Proc(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Path != "/system.slice/nginx.service" || !group.Exists() {
+		t.Errorf("expected the existing group of nginx, got %q", group.Path)
+	}
+	if parent := group.Parent(); parent.Path != "/system.slice" || parent.IsRoot() {
+		t.Errorf("expected the parent /system.slice, got %q", parent.Path)
+	}
+	if root := group.Parent().Parent(); !root.IsRoot() || root.Parent() != root {
+		t.Errorf("expected the root to be its own parent, got %q", root.Path)
+	}
+
+	if _, err = fs.GroupOf(procFS.Proc(11)); err != ErrNoUnifiedCgroup {
+		t.Errorf("expected no cgroup v2 group, got %v", err)
+	}
+	if _, err = fs.GroupOf(procFS.Proc(12)); !os.IsNotExist(err) {
+		t.Errorf("expected a missing process to not exist, got %v", err)
+	}
+	if fs.Group("missing").Exists() {
+		t.Error("expected a missing group to not exist")
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"a/memory.max":         "1073741824\n",
+		"a/b/memory.max":       "max\n",
+		"a/b/c/memory.max":     "2147483648\n",
+		"d/memory.max":         "max\n",
+		"d/cgroup.procs":       "",
+		"e/memory.max":         "lots\n",
+		"a/b/c/d/cgroup.procs": "",
+	})
+
+	for groupPath, expected := range map[string]int64{
+		"/a/b/c":   1073741824,
+		"/a/b/c/d": 1073741824,
+		"/a/b":     1073741824,
+		"/d":       Max,
+	} {
+		var limit, err = fs.Group(groupPath).MemoryLimit()
+		if err != nil || limit != expected {
+			t.Errorf("%s: expected limit %d, got %d %v", groupPath, expected,
+				limit, err)
+		}
+	}
+
+	if max, err := fs.Group("/a/b").MemoryMax(); err != nil || max != Max {
+		t.Errorf("expected no limit on /a/b, got %d %v", max, err)
+	}
+	if _, err := fs.Group("/e").MemoryLimit(); err == nil {
+		t.Error("expected an invalid limit to be an error")
+	}
+}
+
+func TestStats(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"app/cpu.stat": "usage_usec 5000000\nuser_usec 3000000\n" +
+			"system_usec 2000000\nnr_periods 100\nnr_throttled 7\n" +
+			"throttled_usec 350000\n",
+		"app/memory.current": "104857600\n",
+		"app/memory.events":  "low 0\nhigh 3\nmax 2\noom 1\noom_kill 1\n",
+		"app/pids.current":   "12\n",
+		"app/pids.max":       "max\n",
+		"app/io.stat": "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n" +
+			"8:16 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=512 dios=1\n",
+		"app/cpu.pressure":    "some avg10=2.50 avg60=1.00 avg300=0.50 total=1000\n",
+		"bad/io.stat":         "8:0 rbytes=lots\n",
+		"bad/memory.events":   "oom_kill many\n",
+		"notcpu/memory.stat":  "anon 0\n",
+		"notcpu/pids.current": "1\n",
+	})
+	var group = fs.Group("/app")
+
+	var cpu, err = group.CPUStat()
+	var expectedCPU = CPUStat{UsageUsec: 5000000, UserUsec: 3000000,
+		SystemUsec: 2000000, NrPeriods: 100, NrThrottled: 7, ThrottledUsec: 350000}
+	if err != nil || cpu != expectedCPU {
+		t.Errorf("expected %+v, got %+v %v", expectedCPU, cpu, err)
+	}
+
+	var memory int64
+	if memory, err = group.MemoryCurrent(); err != nil || memory != 104857600 {
+		t.Errorf("expected 104857600 bytes of memory, got %d %v", memory, err)
+	}
+
+	var events MemoryEvents
+	events, err = group.MemoryEvents()
+	var expectedEvents = MemoryEvents{High: 3, Max: 2, OOM: 1, OOMKill: 1}
+	if err != nil || events != expectedEvents {
+		t.Errorf("expected %+v, got %+v %v", expectedEvents, events, err)
+	}
+
+	var pids int64
+	if pids, err = group.PidsCurrent(); err != nil || pids != 12 {
+		t.Errorf("expected 12 pids, got %d %v", pids, err)
+	}
+	if pids, err = group.PidsMax(); err != nil || pids != Max {
+		t.Errorf("expected no pids limit, got %d %v", pids, err)
+	}
+
+	var io map[string]IOStat
+	io, err = group.IOStat()
+	var expectedIO = map[string]IOStat{
+		"8:0":  {ReadBytes: 4096, WriteBytes: 8192, ReadIOs: 1, WriteIOs: 2},
+		"8:16": {ReadBytes: 1024, ReadIOs: 1, DiscardBytes: 512, DiscardIOs: 1},
+	}
+	if err != nil || !reflect.DeepEqual(io, expectedIO) {
+		t.Errorf("expected %+v, got %+v %v", expectedIO, io, err)
+	}
+
+	var pressure procfs.Pressure
+	pressure, err = group.Pressure(procfs.PressureCPU)
+	if err != nil || pressure.Some.Avg10 != 2.5 || pressure.Some.Total != 1000 {
+		t.Errorf("expected some cpu pressure, got %+v %v", pressure, err)
+	}
+
+	if _, err = fs.Group("/bad").IOStat(); err == nil {
+		t.Error("expected an invalid io.stat to be an error")
+	}
+	if _, err = fs.Group("/bad").MemoryEvents(); err == nil {
+		t.Error("expected an invalid memory.events to be an error")
+	}
+
+	// Files of controllers that are not enabled do not exist
+	if _, err = fs.Group("/notcpu").CPUStat(); !os.IsNotExist(err) {
+		t.Errorf("expected no cpu.stat, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package cgroups
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// CPUStat is the CPU usage of a group from cpu.stat. Times are in
+// microseconds. The throttling fields are only set when the cpu controller is
+// enabled for the group
+type CPUStat struct {
+	UsageUsec     uint64
+	UserUsec      uint64
+	SystemUsec    uint64
+	NrPeriods     uint64
+	NrThrottled   uint64
+	ThrottledUsec uint64
+}
+
+// CPUStat gets the CPU usage of the group
+func (group Group) CPUStat() (CPUStat, error) {
+	var values, err = group.readKeyedValues("cpu.stat")
+	if err != nil {
+		return CPUStat{}, err
+	}
+
+	return CPUStat{
+		UsageUsec:     values["usage_usec"],
+		UserUsec:      values["user_usec"],
+		SystemUsec:    values["system_usec"],
+		NrPeriods:     values["nr_periods"],
+		NrThrottled:   values["nr_throttled"],
+		ThrottledUsec: values["throttled_usec"],
+	}, nil
+}
+
+// MemoryCurrent gets the memory in bytes used by the group and its
+// descendants
+func (group Group) MemoryCurrent() (int64, error) {
+	return group.readValue("memory.current")
+}
+
+// MemoryMax gets the memory limit in bytes of the group, or Max if it has none
+func (group Group) MemoryMax() (int64, error) {
+	return group.readValue("memory.max")
+}
+
+// MemoryLimit gets the lowest memory limit in bytes of the group and the
+// groups above it, which is the limit the group is held to, or Max if none of
+// them have one. Groups without the memory controller enabled are skipped
+func (group Group) MemoryLimit() (int64, error) {
+	var limit = Max
+	for current := group; ; current = current.Parent() {
+		var groupLimit, err = current.MemoryMax()
+		if err != nil && !os.IsNotExist(err) {
+			return limit, err
+		}
+		if err == nil && groupLimit != Max && (limit == Max || groupLimit < limit) {
+			limit = groupLimit
+		}
+
+		if current.IsRoot() {
+			return limit, nil
+		}
+	}
+}
+
+// MemoryEvents is the number of times the group hit its memory limits, from
+// memory.events
+type MemoryEvents struct {
+	Low          uint64
+	High         uint64
+	Max          uint64
+	OOM          uint64
+	OOMKill      uint64
+	OOMGroupKill uint64
+}
+
+// MemoryEvents gets the memory events of the group and its descendants
+func (group Group) MemoryEvents() (MemoryEvents, error) {
+	var values, err = group.readKeyedValues("memory.events")
+	if err != nil {
+		return MemoryEvents{}, err
+	}
+
+	return MemoryEvents{
+		Low:          values["low"],
+		High:         values["high"],
+		Max:          values["max"],
+		OOM:          values["oom"],
+		OOMKill:      values["oom_kill"],
+		OOMGroupKill: values["oom_group_kill"],
+	}, nil
+}
+
+// IOStat is the IO performed by a group on one device, from io.stat
+type IOStat struct {
+	ReadBytes    uint64
+	WriteBytes   uint64
+	ReadIOs      uint64
+	WriteIOs     uint64
+	DiscardBytes uint64
+	DiscardIOs   uint64
+}
+
+// IOStat gets the IO performed by the group on each device, by its
+// "major:minor" numbers
+func (group Group) IOStat() (map[string]IOStat, error) {
+	var content, err = group.readString("io.stat")
+	if err != nil {
+		return nil, err
+	}
+
+	var stats = make(map[string]IOStat)
+	for _, line := range strings.Split(content, "\n") {
+		var fields = strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var stat IOStat
+		var counters = map[string]*uint64{
+			"rbytes": &stat.ReadBytes,
+			"wbytes": &stat.WriteBytes,
+			"rios":   &stat.ReadIOs,
+			"wios":   &stat.WriteIOs,
+			"dbytes": &stat.DiscardBytes,
+			"dios":   &stat.DiscardIOs,
+		}
+
+		for _, field := range fields[1:] {
+			var kv = strings.SplitN(field, "=", 2)
+			if counter, ok := counters[kv[0]]; ok && len(kv) == 2 {
+				var value, parseErr = strconv.ParseUint(kv[1], 10, 64)
+				if parseErr != nil {
+					return nil, &procfs.ParseError{File: group.file("io.stat"),
+						Msg: "invalid field " + field}
+				}
+				*counter = value
+			}
+		}
+		stats[fields[0]] = stat
+	}
+	return stats, nil
+}
+
+// PidsCurrent gets the number of processes in the group and its descendants
+func (group Group) PidsCurrent() (int64, error) {
+	return group.readValue("pids.current")
+}
+
+// PidsMax gets the limit on the number of processes in the group, or Max if it
+// has none
+func (group Group) PidsMax() (int64, error) {
+	return group.readValue("pids.max")
+}
+
+// Pressure gets the pressure stall information for a resource of the group,
+// such as procfs.PressureCPU
+func (group Group) Pressure(resource string) (procfs.Pressure, error) {
+	var content, err = group.readString(resource + ".pressure")
+	if err != nil {
+		return procfs.Pressure{}, err
+	}
+	return procfs.ParsePressure(group.file(resource+".pressure"), content)
+}
@@ -0,0 +1,25 @@
+package core
+
+// FileName is a key in WatchEvent for a file name
+var FileName = "file.name"
+
+// FileModTime is a key in WatchEvent for a file's modified time
+var FileModTime = "file.modifiedtime"
+
+// FileOperation is a key in WatchEvent for the operation performed on a file
+var FileOperation = "file.operation"
+
+// FileCreated is the FileOperation when a file is created
+var FileCreated = "create"
+
+// FileModified is the FileOperation when a file is modified
+var FileModified = "modify"
+
+// FileDeleted is the FileOperation when a file is deleted
+var FileDeleted = "delete"
+
+// FileRenamed is the FileOperation when a file is renamed or moved
+var FileRenamed = "rename"
+
+// FileAttributes is the FileOperation when a file's attributes change
+var FileAttributes = "attribute"
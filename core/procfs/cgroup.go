@@ -0,0 +1,67 @@
+package procfs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Cgroup is a cgroup hierarchy that a process is in, from /proc/<pid>/cgroup.
+// The unified cgroup v2 hierarchy has ID 0 and no controllers
+type Cgroup struct {
+	ID          int
+	Controllers []string
+	Path        string
+}
+
+// Cgroups gets the cgroups the process is in
+func (proc Proc) Cgroups() ([]Cgroup, error) {
+	var content, err = proc.Cgroup()
+	if err != nil {
+		return nil, err
+	}
+	return parseCgroups(proc.path("cgroup"), content)
+}
+
+// UnifiedCgroup gets the path of the cgroup v2 group the process is in, or an
+// empty string if it is not in one
+func (proc Proc) UnifiedCgroup() (string, error) {
+	var cgroups, err = proc.Cgroups()
+	if err != nil {
+		return "", err
+	}
+
+	for _, cgroup := range cgroups {
+		if cgroup.ID == 0 && len(cgroup.Controllers) == 0 {
+			return cgroup.Path, nil
+		}
+	}
+	return "", nil
+}
+
+// parseCgroups parses the contents of a cgroup file. The path can contain
+// colons, so only the first two separate the fields
+func parseCgroups(file string, content string) ([]Cgroup, error) {
+	var cgroups []Cgroup
+	for _, line := range strings.Split(content, "\n") {
+		if len(line) == 0 {
+			continue
+		}
+
+		var fields = strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			return nil, &ParseError{File: file, Msg: "invalid line " + strconv.Quote(line)}
+		}
+
+		var id, err = parseInt(file, fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		var cgroup = Cgroup{ID: int(id), Path: fields[2]}
+		if len(fields[1]) > 0 {
+			cgroup.Controllers = strings.Split(fields[1], ",")
+		}
+		cgroups = append(cgroups, cgroup)
+	}
+	return cgroups, nil
+}
@@ -0,0 +1,43 @@
+package procfs
+
+import (
+	"strings"
+)
+
+// Cmdline gets the command line arguments of the process. Kernel threads and
+// zombies have no arguments
+func (proc Proc) Cmdline() ([]string, error) {
+	var content, err = proc.readString("cmdline")
+	if err != nil {
+		return nil, err
+	}
+	return splitNulls(content), nil
+}
+
+// Environ gets the environment variables the process started with, by their
+// names
+func (proc Proc) Environ() (map[string]string, error) {
+	var content, err = proc.readString("environ")
+	if err != nil {
+		return nil, err
+	}
+
+	var environ = make(map[string]string)
+	for _, variable := range splitNulls(content) {
+		var kv = strings.SplitN(variable, "=", 2)
+		if len(kv) == 2 {
+			environ[kv[0]] = kv[1]
+		} else {
+			environ[kv[0]] = ""
+		}
+	}
+	return environ, nil
+}
+
+// splitNulls splits a list of null terminated strings
+func splitNulls(content string) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\x00"), "\x00")
+}
@@ -0,0 +1,52 @@
+package procfs
+
+// ProcIO is the IO counters of a process from /proc/<pid>/io
+type ProcIO struct {
+	RChar               uint64
+	WChar               uint64
+	SyscR               uint64
+	SyscW               uint64
+	ReadBytes           uint64
+	WriteBytes          uint64
+	CancelledWriteBytes uint64
+}
+
+// IO gets the IO counters of the process from its io file. Reading it needs
+// the same privileges as tracing the process
+func (proc Proc) IO() (ProcIO, error) {
+	var content, err = proc.readString("io")
+	if err != nil {
+		return ProcIO{}, err
+	}
+	return parseIO(proc.path("io"), content)
+}
+
+// parseIO parses the contents of an io file
+func parseIO(file string, content string) (ProcIO, error) {
+	var io ProcIO
+	var counters = map[string]*uint64{
+		"rchar":                 &io.RChar,
+		"wchar":                 &io.WChar,
+		"syscr":                 &io.SyscR,
+		"syscw":                 &io.SyscW,
+		"read_bytes":            &io.ReadBytes,
+		"write_bytes":           &io.WriteBytes,
+		"cancelled_write_bytes": &io.CancelledWriteBytes,
+	}
+
+	var fields = parseKeyValues(content)
+	for name, counter := range counters {
+		var field, ok = fields[name]
+		if !ok {
+			return io, &ParseError{File: file, Msg: "no " + name}
+		}
+
+		var value, err = parseUint(file, field)
+		if err != nil {
+			return io, err
+		}
+		*counter = value
+	}
+
+	return io, nil
+}
@@ -0,0 +1,102 @@
+package procfs
+
+import (
+	"strings"
+)
+
+// Unlimited is the value of a limit that has no limit
+const Unlimited int64 = -1
+
+// The names of the limits in ProcLimits
+const (
+	LimitCPUTime         = "Max cpu time"
+	LimitFileSize        = "Max file size"
+	LimitDataSize        = "Max data size"
+	LimitStackSize       = "Max stack size"
+	LimitCoreFileSize    = "Max core file size"
+	LimitResidentSet     = "Max resident set"
+	LimitProcesses       = "Max processes"
+	LimitOpenFiles       = "Max open files"
+	LimitLockedMemory    = "Max locked memory"
+	LimitAddressSpace    = "Max address space"
+	LimitFileLocks       = "Max file locks"
+	LimitPendingSignals  = "Max pending signals"
+	LimitMsgqueueSize    = "Max msgqueue size"
+	LimitNicePriority    = "Max nice priority"
+	LimitRealtimePrio    = "Max realtime priority"
+	LimitRealtimeTimeout = "Max realtime timeout"
+)
+
+// Limit is a resource limit of a process. Soft and Hard are Unlimited if the
+// resource has no limit
+type Limit struct {
+	Soft  int64
+	Hard  int64
+	Units string
+}
+
+// ProcLimits is the resource limits of a process from /proc/<pid>/limits, by
+// their names
+type ProcLimits map[string]Limit
+
+// Limits gets the resource limits of the process from its limits file
+func (proc Proc) Limits() (ProcLimits, error) {
+	var content, err = proc.readString("limits")
+	if err != nil {
+		return nil, err
+	}
+	return parseLimits(proc.path("limits"), content)
+}
+
+// parseLimits parses the contents of a limits file. The names of the limits
+// contain spaces, so the columns are found from the header line
+func parseLimits(file string, content string) (ProcLimits, error) {
+	var lines = strings.Split(content, "\n")
+	var header = lines[0]
+
+	var softColumn = strings.Index(header, "Soft Limit")
+	var hardColumn = strings.Index(header, "Hard Limit")
+	var unitsColumn = strings.Index(header, "Units")
+	if softColumn < 0 || hardColumn < softColumn || unitsColumn < hardColumn {
+		return nil, &ParseError{File: file, Msg: "no header"}
+	}
+
+	var limits = make(ProcLimits)
+	for _, line := range lines[1:] {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		if len(line) < hardColumn {
+			return nil, &ParseError{File: file, Msg: "short line " + line}
+		}
+
+		var name = strings.TrimSpace(line[:softColumn])
+		var values = strings.Fields(line[softColumn:])
+		if len(values) < 2 {
+			return nil, &ParseError{File: file, Msg: "no values for " + name}
+		}
+
+		var limit Limit
+		var err error
+		if limit.Soft, err = parseLimit(file, values[0]); err != nil {
+			return nil, err
+		}
+		if limit.Hard, err = parseLimit(file, values[1]); err != nil {
+			return nil, err
+		}
+		if len(values) > 2 {
+			limit.Units = values[2]
+		}
+		limits[name] = limit
+	}
+
+	return limits, nil
+}
+
+// parseLimit parses the value of a limit
+func parseLimit(file string, value string) (int64, error) {
+	if value == "unlimited" {
+		return Unlimited, nil
+	}
+	return parseInt(file, value)
+}
@@ -0,0 +1,60 @@
+package procfs
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NetTable gets the contents of a table in the net directory, such as tcp,
+// udp6 or dev
+func (fs FS) NetTable(name string) (string, error) {
+	return fs.readString("net", name)
+}
+
+// SocketOwners gets the pid of the process owning each socket inode. Sockets
+// owned by processes whose fds cannot be read are not included
+func (fs FS) SocketOwners() (map[uint64]int, error) {
+	var pids, err = fs.Pids()
+	if err != nil {
+		return nil, err
+	}
+
+	var owners = make(map[uint64]int)
+	for _, pid := range pids {
+		var inodes, inodesErr = fs.Proc(pid).SocketInodes()
+		if inodesErr != nil {
+			continue
+		}
+		for _, inode := range inodes {
+			owners[inode] = pid
+		}
+	}
+	return owners, nil
+}
+
+// SocketInodes gets the inodes of the sockets the process has open. Reading
+// them needs access to the fds of the process
+func (proc Proc) SocketInodes() ([]uint64, error) {
+	var fdDir = proc.path("fd")
+	var fds, err = ioutil.ReadDir(fdDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var inodes []uint64
+	for _, fd := range fds {
+		var link, linkErr = os.Readlink(proc.path("fd", fd.Name()))
+		if linkErr != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+
+		var inode, parseErr = strconv.ParseUint(
+			strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+		if parseErr == nil {
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes, nil
+}
@@ -0,0 +1,72 @@
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// socketFixture creates a proc filesystem with processes holding fds
+func socketFixture(t *testing.T, fds map[string]map[string]string) FS {
+	var root = t.TempDir()
+	for pid, links := range fds {
+		var fdDir = filepath.Join(root, pid, "fd")
+		if err := os.MkdirAll(fdDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for fd, target := range links {
+			if err := os.Symlink(target, filepath.Join(fdDir, fd)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return NewFS(root)
+}
+
+func TestSocketInodes(t *testing.T) {
+	var fs = socketFixture(t, map[string]map[string]string{
+		"10": {
+			"0": "/dev/null",
+			"3": "socket:[1234]",
+			"4": "pipe:[99]",
+			"5": "socket:[5678]",
+		},
+	})
+
+	var inodes, err = fs.Proc(10).SocketInodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(inodes, func(i, j int) bool { return inodes[i] < inodes[j] })
+	if !reflect.DeepEqual(inodes, []uint64{1234, 5678}) {
+		t.Errorf("expected inodes [1234 5678], got %v", inodes)
+	}
+
+	if _, err = fs.Proc(11).SocketInodes(); !os.IsNotExist(err) {
+		t.Errorf("expected a missing process to not exist, got %v", err)
+	}
+}
+
+func TestSocketOwners(t *testing.T) {
+	var fs = socketFixture(t, map[string]map[string]string{
+		"10": {"3": "socket:[1234]"},
+		"20": {"3": "socket:[5678]", "4": "socket:[9012]"},
+		"30": {"1": "/dev/null"},
+	})
+	// Entries that are not processes are ignored
+	if err := os.Mkdir(fs.Path("net"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var owners, err = fs.SocketOwners()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = map[uint64]int{1234: 10, 5678: 20, 9012: 20}
+	if !reflect.DeepEqual(owners, expected) {
+		t.Errorf("expected owners %v, got %v", expected, owners)
+	}
+}
@@ -0,0 +1,85 @@
+package procfs
+
+import (
+	"strings"
+)
+
+// The resources that have pressure stall information
+const (
+	PressureCPU    = "cpu"
+	PressureMemory = "memory"
+	PressureIO     = "io"
+)
+
+// PressureStats are the shares of time that tasks were stalled on a resource,
+// as percentages averaged over 10, 60 and 300 seconds, and the total time
+// stalled in microseconds
+type PressureStats struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// Pressure is the pressure stall information for a resource. Some is when at
+// least one task was stalled and Full is when all non-idle tasks were
+type Pressure struct {
+	Some PressureStats
+	Full PressureStats
+}
+
+// Pressure gets the pressure stall information for the system from
+// /proc/pressure/<resource>, which needs a kernel with CONFIG_PSI
+func (fs FS) Pressure(resource string) (Pressure, error) {
+	var content, err = fs.readString("pressure", resource)
+	if err != nil {
+		return Pressure{}, err
+	}
+	return ParsePressure(fs.Path("pressure", resource), content)
+}
+
+// ParsePressure parses the contents of a pressure file, which has the same
+// format in /proc/pressure and cgroups. The cpu file had no full line before
+// Linux 5.13
+func ParsePressure(file string, content string) (Pressure, error) {
+	var pressure Pressure
+	for _, line := range strings.Split(content, "\n") {
+		var fields = strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var stats *PressureStats
+		switch fields[0] {
+		case "some":
+			stats = &pressure.Some
+		case "full":
+			stats = &pressure.Full
+		default:
+			return pressure, &ParseError{File: file, Msg: "unknown line " + fields[0]}
+		}
+
+		for _, field := range fields[1:] {
+			var kv = strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return pressure, &ParseError{File: file, Msg: "invalid field " + field}
+			}
+
+			var err error
+			switch kv[0] {
+			case "avg10":
+				stats.Avg10, err = parseFloat(file, kv[1])
+			case "avg60":
+				stats.Avg60, err = parseFloat(file, kv[1])
+			case "avg300":
+				stats.Avg300, err = parseFloat(file, kv[1])
+			case "total":
+				stats.Total, err = parseUint(file, kv[1])
+			}
+			if err != nil {
+				return pressure, err
+			}
+		}
+	}
+	return pressure, nil
+}
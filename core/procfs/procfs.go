@@ -0,0 +1,211 @@
+// Package procfs reads process and system information from the proc
+// filesystem. The root of the filesystem can be changed, so it can read from
+// a copy of /proc, such as one from a container or a directory of fixtures
+package procfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultRoot is where the proc filesystem is usually mounted
+const DefaultRoot = "/proc"
+
+// FS is a proc filesystem mounted at a root directory
+type FS struct {
+	root string
+}
+
+// Default is the proc filesystem mounted at DefaultRoot
+var Default = NewFS(DefaultRoot)
+
+// NewFS creates a new FS for the proc filesystem mounted at root
+func NewFS(root string) FS {
+	return FS{root: root}
+}
+
+// Root gets the directory the filesystem is mounted at
+func (fs FS) Root() string {
+	return fs.root
+}
+
+// Path gets the path of a file in the filesystem
+func (fs FS) Path(elem ...string) string {
+	return filepath.Join(append([]string{fs.root}, elem...)...)
+}
+
+// Proc gets a process in the filesystem. The process may not exist
+func (fs FS) Proc(pid int) Proc {
+	return Proc{PID: pid, fs: fs}
+}
+
+// Pids gets the pids of all the processes in the filesystem, in order
+func (fs FS) Pids() ([]int, error) {
+	var entries, err = ioutil.ReadDir(fs.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		if pid, parseErr := strconv.Atoi(entry.Name()); parseErr == nil {
+			pids = append(pids, pid)
+		}
+	}
+
+	sort.Ints(pids)
+	return pids, nil
+}
+
+// Uptime gets the number of seconds since the system booted
+func (fs FS) Uptime() (float64, error) {
+	var content, err = fs.readString("uptime")
+	if err != nil {
+		return 0, err
+	}
+
+	var fields = strings.Fields(content)
+	if len(fields) == 0 {
+		return 0, &ParseError{File: fs.Path("uptime"), Msg: "no fields"}
+	}
+	return parseFloat(fs.Path("uptime"), fields[0])
+}
+
+// readString reads a file in the filesystem as a string
+func (fs FS) readString(elem ...string) (string, error) {
+	var content, err = ioutil.ReadFile(fs.Path(elem...))
+	return string(content), err
+}
+
+// Proc is a process in a proc filesystem
+type Proc struct {
+	PID int
+	fs  FS
+}
+
+// Exists returns true if the process exists
+func (proc Proc) Exists() bool {
+	var _, err = os.Stat(proc.path())
+	return err == nil
+}
+
+// Comm gets the command name of the process
+func (proc Proc) Comm() (string, error) {
+	var comm, err = proc.readString("comm")
+	return strings.TrimSuffix(comm, "\n"), err
+}
+
+// Exe gets the path of the executable the process is running
+func (proc Proc) Exe() (string, error) {
+	return os.Readlink(proc.path("exe"))
+}
+
+// Cgroup gets the contents of the cgroup file of the process
+func (proc Proc) Cgroup() (string, error) {
+	return proc.readString("cgroup")
+}
+
+// FDCount gets the number of file descriptors the process has open
+func (proc Proc) FDCount() (int, error) {
+	var dir, err = os.Open(proc.path("fd"))
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+
+	var names, readErr = dir.Readdirnames(-1)
+	return len(names), readErr
+}
+
+// Children gets the pids of the children of all the threads of the process.
+// Reading them needs a kernel with CONFIG_PROC_CHILDREN
+func (proc Proc) Children() ([]int, error) {
+	var tasks, err = filepath.Glob(proc.path("task", "*", "children"))
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, os.ErrNotExist
+	}
+
+	var children []int
+	for _, task := range tasks {
+		var content, readErr = ioutil.ReadFile(task)
+		if readErr != nil {
+			return nil, readErr
+		}
+		for _, field := range strings.Fields(string(content)) {
+			var child, parseErr = parseInt(task, field)
+			if parseErr != nil {
+				return nil, parseErr
+			}
+			children = append(children, int(child))
+		}
+	}
+	return children, nil
+}
+
+// path gets the path of a file in the process directory
+func (proc Proc) path(elem ...string) string {
+	return proc.fs.Path(append([]string{strconv.Itoa(proc.PID)}, elem...)...)
+}
+
+// readString reads a file in the process directory as a string
+func (proc Proc) readString(name string) (string, error) {
+	var content, err = ioutil.ReadFile(proc.path(name))
+	return string(content), err
+}
+
+// ParseError is returned when a file in the filesystem is not in the expected
+// format
+type ParseError struct {
+	File string
+	Msg  string
+}
+
+func (err *ParseError) Error() string {
+	return "procfs: failed to parse " + err.File + ": " + err.Msg
+}
+
+// parseInt parses a signed number from a file
+func parseInt(file string, value string) (int64, error) {
+	var number, err = strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &ParseError{File: file, Msg: "invalid number " + strconv.Quote(value)}
+	}
+	return number, nil
+}
+
+// parseUint parses an unsigned number from a file
+func parseUint(file string, value string) (uint64, error) {
+	var number, err = strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, &ParseError{File: file, Msg: "invalid number " + strconv.Quote(value)}
+	}
+	return number, nil
+}
+
+// parseFloat parses a decimal number from a file
+func parseFloat(file string, value string) (float64, error) {
+	var number, err = strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, &ParseError{File: file, Msg: "invalid number " + strconv.Quote(value)}
+	}
+	return number, nil
+}
+
+// parseKeyValues parses the "key: value" lines of a file
+func parseKeyValues(content string) map[string]string {
+	var values = make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		var kv = strings.SplitN(line, ":", 2)
+		if len(kv) == 2 {
+			values[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return values
+}
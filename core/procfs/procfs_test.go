@@ -0,0 +1,310 @@
+package procfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fixture creates a proc filesystem containing the files
+func fixture(t *testing.T, files map[string]string) FS {
+	var root = t.TempDir()
+	for name, content := range files {
+		var path = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewFS(root)
+}
+
+func TestStat(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"10/stat": "10 (a (b) c) S 1 10 10 0 -1 4194560 1200 300 4 1 250 75 " +
+			"-3 -2 20 0 3 0 12345 104857600 2560 18446744073709551615\n",
+		"11/stat": "11 (short) S 1 11\n",
+		"12/stat": "12 (bad) S x 12 12 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 1 1 1\n",
+	})
+
+	var stat, err = fs.Proc(10).Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Comm != "a (b) c" || stat.State != "S" {
+		t.Errorf("expected command a (b) c in state S, got %q %q",
+			stat.Comm, stat.State)
+	}
+	var expected = ProcStat{PID: 10, PPID: 1, PGRP: 10, Session: 10, TTY: 0,
+		TPGID: -1, Flags: 4194560, MinFlt: 1200, CMinFlt: 300, MajFlt: 4,
+		CMajFlt: 1, UTime: 250, STime: 75, CUTime: -3, CSTime: -2,
+		Priority: 20, Nice: 0, NumThreads: 3, StartTime: 12345,
+		VSize: 104857600, RSS: 2560}
+	expected.Comm = stat.Comm
+	expected.State = stat.State
+	expected.Fields = stat.Fields
+	if !reflect.DeepEqual(stat, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stat)
+	}
+	if stat.Fields[1] != "(a (b) c)" || len(stat.Fields) != 25 {
+		t.Errorf("expected the command to be one field, got %q", stat.Fields)
+	}
+
+	if _, err = fs.Proc(11).Stat(); err == nil {
+		t.Error("expected too few fields to be an error")
+	}
+	if _, err = fs.Proc(12).Stat(); err == nil {
+		t.Error("expected an invalid ppid to be an error")
+	}
+	if _, err = fs.Proc(13).Stat(); !os.IsNotExist(err) {
+		t.Errorf("expected a missing process to not exist, got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"10/status": "Name:\tnginx\n" +
+			"State:\tS (sleeping)\n" +
+			"Tgid:\t10\n" +
+			"Pid:\t10\n" +
+			"PPid:\t1\n" +
+			"Uid:\t33\t33\t33\t33\n" +
+			"Gid:\t33\t33\t33\t34\n" +
+			"FDSize:\t64\n" +
+			"VmPeak:\t  20480 kB\n" +
+			"VmSize:\t  16384 kB\n" +
+			"VmHWM:\t    8192 kB\n" +
+			"VmRSS:\t    4096 kB\n" +
+			"Threads:\t2\n" +
+			"voluntary_ctxt_switches:\t150\n" +
+			"nonvoluntary_ctxt_switches:\t7\n",
+		"11/status": "Name:\tbad\nPid:\televen\n",
+	})
+
+	var status, err = fs.Proc(10).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Name != "nginx" || status.State != "S (sleeping)" {
+		t.Errorf("expected nginx S (sleeping), got %q %q", status.Name,
+			status.State)
+	}
+	if status.PID != 10 || status.TGID != 10 || status.PPID != 1 ||
+		status.FDSize != 64 || status.Threads != 2 {
+		t.Errorf("expected pid 10 tgid 10 ppid 1 fdsize 64 threads 2, got %+v",
+			status)
+	}
+	if status.UIDs != [4]int{33, 33, 33, 33} || status.GIDs != [4]int{33, 33, 33, 34} {
+		t.Errorf("expected uids 33 and gids 33 34, got %v %v", status.UIDs,
+			status.GIDs)
+	}
+	if status.VmPeak != 20480*1024 || status.VmSize != 16384*1024 ||
+		status.VmHWM != 8192*1024 || status.VmRSS != 4096*1024 {
+		t.Errorf("expected memory sizes in bytes, got %+v", status)
+	}
+	// Kernels without swap accounting have no VmSwap
+	if status.VmSwap != 0 {
+		t.Errorf("expected no swap, got %d", status.VmSwap)
+	}
+	if status.VoluntaryCtxtSwitches != 150 || status.NonvoluntaryCtxtSwitches != 7 {
+		t.Errorf("expected 150 and 7 context switches, got %d %d",
+			status.VoluntaryCtxtSwitches, status.NonvoluntaryCtxtSwitches)
+	}
+
+	if _, err = fs.Proc(11).Status(); err == nil {
+		t.Error("expected an invalid pid to be an error")
+	}
+}
+
+func TestIO(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"10/io": "rchar: 1000\nwchar: 2000\nsyscr: 30\nsyscw: 40\n" +
+			"read_bytes: 4096\nwrite_bytes: 8192\ncancelled_write_bytes: 512\n",
+		"11/io": "rchar: 1000\nwchar: 2000\n",
+	})
+
+	var io, err = fs.Proc(10).IO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = ProcIO{RChar: 1000, WChar: 2000, SyscR: 30, SyscW: 40,
+		ReadBytes: 4096, WriteBytes: 8192, CancelledWriteBytes: 512}
+	if io != expected {
+		t.Errorf("expected %+v, got %+v", expected, io)
+	}
+
+	if _, err = fs.Proc(11).IO(); err == nil {
+		t.Error("expected missing counters to be an error")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"10/limits": "Limit                     Soft Limit           Hard Limit           Units     \n" +
+			"Max cpu time              unlimited            unlimited            seconds   \n" +
+			"Max processes             63432                63432                processes \n" +
+			"Max open files            1024                 524288               files     \n" +
+			"Max nice priority         0                    0                    \n",
+		"11/limits": "Max open files 1024 4096 files\n",
+	})
+
+	var limits, err = fs.Proc(10).Limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = ProcLimits{
+		LimitCPUTime:      {Soft: Unlimited, Hard: Unlimited, Units: "seconds"},
+		LimitProcesses:    {Soft: 63432, Hard: 63432, Units: "processes"},
+		LimitOpenFiles:    {Soft: 1024, Hard: 524288, Units: "files"},
+		LimitNicePriority: {Soft: 0, Hard: 0},
+	}
+	if !reflect.DeepEqual(limits, expected) {
+		t.Errorf("expected %v, got %v", expected, limits)
+	}
+
+	if _, err = fs.Proc(11).Limits(); err == nil {
+		t.Error("expected a file with no header to be an error")
+	}
+}
+
+func TestStatm(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"10/statm": "4000 1000 500 20 0 900 0\n",
+		"11/statm": "4000 1000\n",
+	})
+
+	var statm, err = fs.Proc(10).Statm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = ProcStatm{Size: 4000, Resident: 1000, Shared: 500,
+		Text: 20, Data: 900}
+	if statm != expected {
+		t.Errorf("expected %+v, got %+v", expected, statm)
+	}
+
+	if _, err = fs.Proc(11).Statm(); err == nil {
+		t.Error("expected too few fields to be an error")
+	}
+}
+
+func TestSmapsRollup(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"10/smaps_rollup": "00400000-7ffc0000 ---p 00000000 00:00 0                  [rollup]\n" +
+			"Rss:                1000 kB\n" +
+			"Pss:                 600 kB\n" +
+			"Pss_Anon:            400 kB\n" +
+			"Pss_File:            150 kB\n" +
+			"Pss_Shmem:            50 kB\n" +
+			"Shared_Clean:        300 kB\n" +
+			"Shared_Dirty:        100 kB\n" +
+			"Private_Clean:       200 kB\n" +
+			"Private_Dirty:       400 kB\n" +
+			"Swap:                 64 kB\n" +
+			"SwapPss:              32 kB\n",
+		"11/smaps_rollup": "00400000-7ffc0000 ---p 00000000 00:00 0 [rollup]\n",
+	})
+
+	var rollup, err = fs.Proc(10).SmapsRollup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = ProcSmapsRollup{Rss: 1000 * 1024, Pss: 600 * 1024,
+		PssAnon: 400 * 1024, PssFile: 150 * 1024, PssShmem: 50 * 1024,
+		Shared: 400 * 1024, Private: 600 * 1024, Swap: 64 * 1024,
+		SwapPss: 32 * 1024}
+	if rollup != expected {
+		t.Errorf("expected %+v, got %+v", expected, rollup)
+	}
+
+	if _, err = fs.Proc(11).SmapsRollup(); err == nil {
+		t.Error("expected a rollup with no Rss to be an error")
+	}
+}
+
+func TestCgroups(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"10/cgroup": "12:cpu,cpuacct:/system.slice/nginx.service\n" +
+			"1:name=systemd:/system.slice/nginx.service\n" +
+			"0::/system.slice/nginx.service:with:colons\n",
+		"11/cgroup": "4:memory:/docker/abc\n",
+		"12/cgroup": "not a cgroup\n",
+	})
+
+	var cgroups, err = fs.Proc(10).Cgroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = []Cgroup{
+		{ID: 12, Controllers: []string{"cpu", "cpuacct"}, Path: "/system.slice/nginx.service"},
+		{ID: 1, Controllers: []string{"name=systemd"}, Path: "/system.slice/nginx.service"},
+		{ID: 0, Path: "/system.slice/nginx.service:with:colons"},
+	}
+	if !reflect.DeepEqual(cgroups, expected) {
+		t.Errorf("expected %+v, got %+v", expected, cgroups)
+	}
+
+	var unified string
+	if unified, err = fs.Proc(10).UnifiedCgroup(); err != nil ||
+		unified != "/system.slice/nginx.service:with:colons" {
+		t.Errorf("expected the unified cgroup, got %q %v", unified, err)
+	}
+	if unified, err = fs.Proc(11).UnifiedCgroup(); err != nil || unified != "" {
+		t.Errorf("expected no unified cgroup, got %q %v", unified, err)
+	}
+	if _, err = fs.Proc(12).Cgroups(); err == nil {
+		t.Error("expected an invalid line to be an error")
+	}
+}
+
+func TestProcFiles(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"10/comm":                 "my worker\n",
+		"10/cmdline":              "/usr/bin/worker\x00--name\x00my worker\x00",
+		"10/environ":              "HOME=/root\x00PATH=/bin:/usr/bin\x00EMPTY=\x00",
+		"10/task/10/children":     "20 21 ",
+		"10/task/11/children":     "22",
+		"20/comm":                 "child\n",
+		"not-a-process/something": "",
+	})
+
+	if comm, err := fs.Proc(10).Comm(); err != nil || comm != "my worker" {
+		t.Errorf("expected comm my worker, got %q %v", comm, err)
+	}
+
+	var cmdline, err = fs.Proc(10).Cmdline()
+	if err != nil || !reflect.DeepEqual(cmdline,
+		[]string{"/usr/bin/worker", "--name", "my worker"}) {
+		t.Errorf("expected the arguments, got %q %v", cmdline, err)
+	}
+
+	var environ map[string]string
+	environ, err = fs.Proc(10).Environ()
+	if err != nil || !reflect.DeepEqual(environ, map[string]string{
+		"HOME": "/root", "PATH": "/bin:/usr/bin", "EMPTY": ""}) {
+		t.Errorf("expected the environment, got %v %v", environ, err)
+	}
+
+	var children []int
+	children, err = fs.Proc(10).Children()
+	if err != nil || !reflect.DeepEqual(children, []int{20, 21, 22}) {
+		t.Errorf("expected children [20 21 22], got %v %v", children, err)
+	}
+	if _, err = fs.Proc(20).Children(); !os.IsNotExist(err) {
+		t.Errorf("expected no children file to not exist, got %v", err)
+	}
+
+	var pids []int
+	pids, err = fs.Pids()
+	if err != nil || !reflect.DeepEqual(pids, []int{10, 20}) {
+		t.Errorf("expected pids [10 20], got %v %v", pids, err)
+	}
+
+	if !fs.Proc(10).Exists() || fs.Proc(30).Exists() {
+		t.Error("expected only processes in the filesystem to exist")
+	}
+}
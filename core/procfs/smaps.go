@@ -0,0 +1,64 @@
+package procfs
+
+import (
+	"strings"
+)
+
+// ProcSmapsRollup is the memory usage of a process summed over all its
+// mappings, from /proc/<pid>/smaps_rollup. Sizes are in bytes
+type ProcSmapsRollup struct {
+	Rss      uint64
+	Pss      uint64
+	PssAnon  uint64
+	PssFile  uint64
+	PssShmem uint64
+	Shared   uint64
+	Private  uint64
+	Swap     uint64
+	SwapPss  uint64
+}
+
+// SmapsRollup gets the memory usage of the process from its smaps_rollup
+// file, which needs Linux 4.14 or later
+func (proc Proc) SmapsRollup() (ProcSmapsRollup, error) {
+	var content, err = proc.readString("smaps_rollup")
+	if err != nil {
+		return ProcSmapsRollup{}, err
+	}
+	return parseSmapsRollup(proc.path("smaps_rollup"), content)
+}
+
+// parseSmapsRollup parses the contents of a smaps_rollup file. The first line
+// is the address range of the mappings, which is not used
+func parseSmapsRollup(file string, content string) (ProcSmapsRollup, error) {
+	var rollup ProcSmapsRollup
+	var fields = parseKeyValues(content)
+	if _, ok := fields["Rss"]; !ok {
+		return rollup, &ParseError{File: file, Msg: "no Rss"}
+	}
+
+	var err error
+	var bytes = func(names ...string) uint64 {
+		var total uint64
+		for _, name := range names {
+			if field, ok := fields[name]; ok && err == nil {
+				var value uint64
+				value, err = parseUint(file, strings.TrimSuffix(field, " kB"))
+				total += value * 1024
+			}
+		}
+		return total
+	}
+
+	rollup.Rss = bytes("Rss")
+	rollup.Pss = bytes("Pss")
+	rollup.PssAnon = bytes("Pss_Anon")
+	rollup.PssFile = bytes("Pss_File")
+	rollup.PssShmem = bytes("Pss_Shmem")
+	rollup.Shared = bytes("Shared_Clean", "Shared_Dirty")
+	rollup.Private = bytes("Private_Clean", "Private_Dirty")
+	rollup.Swap = bytes("Swap")
+	rollup.SwapPss = bytes("SwapPss")
+
+	return rollup, err
+}
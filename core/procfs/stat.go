@@ -0,0 +1,113 @@
+package procfs
+
+import (
+	"strings"
+)
+
+// ProcStat is the status of a process from /proc/<pid>/stat. Times are in
+// clock ticks and RSS is in pages
+type ProcStat struct {
+	PID        int
+	Comm       string
+	State      string
+	PPID       int
+	PGRP       int
+	Session    int
+	TTY        int
+	TPGID      int
+	Flags      uint64
+	MinFlt     uint64
+	CMinFlt    uint64
+	MajFlt     uint64
+	CMajFlt    uint64
+	UTime      uint64
+	STime      uint64
+	CUTime     int64
+	CSTime     int64
+	Priority   int64
+	Nice       int64
+	NumThreads int64
+	StartTime  uint64
+	VSize      uint64
+	RSS        int64
+	// Fields are all the fields in the file, with the command name as one
+	// field in its brackets
+	Fields []string
+}
+
+// statFields is the number of fields in the stat file that are parsed
+const statFields = 24
+
+// Stat gets the status of the process from its stat file
+func (proc Proc) Stat() (ProcStat, error) {
+	var content, err = proc.readString("stat")
+	if err != nil {
+		return ProcStat{}, err
+	}
+	return parseStat(proc.path("stat"), content)
+}
+
+// parseStat parses the contents of a stat file. The command name can contain
+// spaces and brackets, so it is everything between the first opening bracket
+// and the last closing bracket
+func parseStat(file string, content string) (ProcStat, error) {
+	var stat ProcStat
+
+	var openBracket = strings.Index(content, "(")
+	var closeBracket = strings.LastIndex(content, ")")
+	if openBracket < 0 || closeBracket < openBracket {
+		return stat, &ParseError{File: file, Msg: "no command name"}
+	}
+
+	var fields = append(strings.Fields(content[:openBracket]),
+		content[openBracket:closeBracket+1])
+	fields = append(fields, strings.Fields(content[closeBracket+1:])...)
+	if len(fields) < statFields {
+		return stat, &ParseError{File: file, Msg: "too few fields"}
+	}
+
+	stat.Fields = fields
+	stat.Comm = content[openBracket+1 : closeBracket]
+	stat.State = fields[2]
+
+	// The fields are numbered from 1, as they are in proc(5)
+	var err error
+	var signed = func(number int) int64 {
+		var value int64
+		if err == nil {
+			value, err = parseInt(file, fields[number-1])
+		}
+		return value
+	}
+	var unsigned = func(number int) uint64 {
+		var value uint64
+		if err == nil {
+			value, err = parseUint(file, fields[number-1])
+		}
+		return value
+	}
+
+	stat.PID = int(signed(1))
+	stat.PPID = int(signed(4))
+	stat.PGRP = int(signed(5))
+	stat.Session = int(signed(6))
+	stat.TTY = int(signed(7))
+	stat.TPGID = int(signed(8))
+	stat.Flags = unsigned(9)
+	stat.MinFlt = unsigned(10)
+	stat.CMinFlt = unsigned(11)
+	stat.MajFlt = unsigned(12)
+	stat.CMajFlt = unsigned(13)
+	stat.UTime = unsigned(14)
+	stat.STime = unsigned(15)
+	stat.CUTime = signed(16)
+	stat.CSTime = signed(17)
+	stat.Priority = signed(18)
+	stat.Nice = signed(19)
+	stat.NumThreads = signed(20)
+	stat.StartTime = unsigned(22)
+	stat.VSize = unsigned(23)
+	stat.RSS = signed(24)
+
+	return stat, err
+}
@@ -0,0 +1,48 @@
+package procfs
+
+import (
+	"strings"
+)
+
+// ProcStatm is the memory usage of a process from /proc/<pid>/statm. Sizes
+// are in pages
+type ProcStatm struct {
+	Size     uint64
+	Resident uint64
+	Shared   uint64
+	Text     uint64
+	Lib      uint64
+	Data     uint64
+	Dirty    uint64
+}
+
+// Statm gets the memory usage of the process from its statm file
+func (proc Proc) Statm() (ProcStatm, error) {
+	var content, err = proc.readString("statm")
+	if err != nil {
+		return ProcStatm{}, err
+	}
+	return parseStatm(proc.path("statm"), content)
+}
+
+// parseStatm parses the contents of a statm file
+func parseStatm(file string, content string) (ProcStatm, error) {
+	var statm ProcStatm
+	var sizes = []*uint64{&statm.Size, &statm.Resident, &statm.Shared,
+		&statm.Text, &statm.Lib, &statm.Data, &statm.Dirty}
+
+	var fields = strings.Fields(content)
+	if len(fields) < len(sizes) {
+		return statm, &ParseError{File: file, Msg: "too few fields"}
+	}
+
+	for i, size := range sizes {
+		var value, err = parseUint(file, fields[i])
+		if err != nil {
+			return statm, err
+		}
+		*size = value
+	}
+
+	return statm, nil
+}
@@ -0,0 +1,97 @@
+package procfs
+
+import (
+	"strings"
+)
+
+// ProcStatus is the status of a process from /proc/<pid>/status. Memory
+// sizes are in bytes
+type ProcStatus struct {
+	Name                     string
+	State                    string
+	TGID                     int
+	PID                      int
+	PPID                     int
+	UIDs                     [4]int
+	GIDs                     [4]int
+	FDSize                   int
+	VmPeak                   uint64
+	VmSize                   uint64
+	VmHWM                    uint64
+	VmRSS                    uint64
+	VmSwap                   uint64
+	Threads                  int
+	VoluntaryCtxtSwitches    uint64
+	NonvoluntaryCtxtSwitches uint64
+	// Fields are all the fields in the file by name, unparsed
+	Fields map[string]string
+}
+
+// Status gets the status of the process from its status file
+func (proc Proc) Status() (ProcStatus, error) {
+	var content, err = proc.readString("status")
+	if err != nil {
+		return ProcStatus{}, err
+	}
+	return parseStatus(proc.path("status"), content)
+}
+
+// parseStatus parses the contents of a status file. Fields that are not in
+// the file, as some depend on the kernel, are left as 0
+func parseStatus(file string, content string) (ProcStatus, error) {
+	var status = ProcStatus{Fields: parseKeyValues(content)}
+	status.Name = status.Fields["Name"]
+	status.State = status.Fields["State"]
+
+	var err error
+	var number = func(name string) int {
+		var value int64
+		if field, ok := status.Fields[name]; ok && err == nil {
+			value, err = parseInt(file, field)
+		}
+		return int(value)
+	}
+	var counter = func(name string) uint64 {
+		var value uint64
+		if field, ok := status.Fields[name]; ok && err == nil {
+			value, err = parseUint(file, field)
+		}
+		return value
+	}
+	var bytes = func(name string) uint64 {
+		var value uint64
+		if field, ok := status.Fields[name]; ok && err == nil {
+			value, err = parseUint(file, strings.TrimSuffix(field, " kB"))
+			value *= 1024
+		}
+		return value
+	}
+	var ids = func(name string) [4]int {
+		var values [4]int
+		for i, field := range strings.Fields(status.Fields[name]) {
+			if i < len(values) && err == nil {
+				var value int64
+				value, err = parseInt(file, field)
+				values[i] = int(value)
+			}
+		}
+		return values
+	}
+
+	status.TGID = number("Tgid")
+	status.PID = number("Pid")
+	status.PPID = number("PPid")
+	status.UIDs = ids("Uid")
+	status.GIDs = ids("Gid")
+	status.FDSize = number("FDSize")
+	status.VmPeak = bytes("VmPeak")
+	status.VmSize = bytes("VmSize")
+	status.VmHWM = bytes("VmHWM")
+	status.VmRSS = bytes("VmRSS")
+	status.VmSwap = bytes("VmSwap")
+	status.Threads = number("Threads")
+	status.VoluntaryCtxtSwitches = counter("voluntary_ctxt_switches")
+	status.NonvoluntaryCtxtSwitches = counter("nonvoluntary_ctxt_switches")
+
+	return status, err
+}
@@ -0,0 +1,254 @@
+package procfs
+
+import (
+	"strings"
+)
+
+// LoadAvg is the system load from /proc/loadavg
+type LoadAvg struct {
+	Load1   float64
+	Load5   float64
+	Load15  float64
+	Running int
+	Total   int
+	LastPID int
+}
+
+// LoadAvg gets the system load averages over 1, 5 and 15 minutes
+func (fs FS) LoadAvg() (LoadAvg, error) {
+	var content, err = fs.readString("loadavg")
+	if err != nil {
+		return LoadAvg{}, err
+	}
+	return parseLoadAvg(fs.Path("loadavg"), content)
+}
+
+// parseLoadAvg parses the contents of a loadavg file
+func parseLoadAvg(file string, content string) (LoadAvg, error) {
+	var load LoadAvg
+	var fields = strings.Fields(content)
+	if len(fields) < 5 {
+		return load, &ParseError{File: file, Msg: "too few fields"}
+	}
+
+	var tasks = strings.SplitN(fields[3], "/", 2)
+	if len(tasks) != 2 {
+		return load, &ParseError{File: file, Msg: "invalid tasks " + fields[3]}
+	}
+
+	var err error
+	var decimal = func(value string) float64 {
+		var number float64
+		if err == nil {
+			number, err = parseFloat(file, value)
+		}
+		return number
+	}
+	var number = func(value string) int {
+		var number int64
+		if err == nil {
+			number, err = parseInt(file, value)
+		}
+		return int(number)
+	}
+
+	load.Load1 = decimal(fields[0])
+	load.Load5 = decimal(fields[1])
+	load.Load15 = decimal(fields[2])
+	load.Running = number(tasks[0])
+	load.Total = number(tasks[1])
+	load.LastPID = number(fields[4])
+
+	return load, err
+}
+
+// MemInfo is the system memory usage from /proc/meminfo. Sizes are in bytes
+type MemInfo struct {
+	MemTotal     uint64
+	MemFree      uint64
+	MemAvailable uint64
+	Buffers      uint64
+	Cached       uint64
+	SwapTotal    uint64
+	SwapFree     uint64
+	// Fields are all the fields in the file by name, converted to bytes where
+	// they are sizes
+	Fields map[string]uint64
+}
+
+// MemInfo gets the system memory usage
+func (fs FS) MemInfo() (MemInfo, error) {
+	var content, err = fs.readString("meminfo")
+	if err != nil {
+		return MemInfo{}, err
+	}
+	return parseMemInfo(fs.Path("meminfo"), content)
+}
+
+// parseMemInfo parses the contents of a meminfo file
+func parseMemInfo(file string, content string) (MemInfo, error) {
+	var info = MemInfo{Fields: make(map[string]uint64)}
+	for name, field := range parseKeyValues(content) {
+		var value, err = parseUint(file, strings.TrimSuffix(field, " kB"))
+		if err != nil {
+			return info, err
+		}
+		if strings.HasSuffix(field, " kB") {
+			value *= 1024
+		}
+		info.Fields[name] = value
+	}
+
+	if _, ok := info.Fields["MemTotal"]; !ok {
+		return info, &ParseError{File: file, Msg: "no MemTotal"}
+	}
+
+	info.MemTotal = info.Fields["MemTotal"]
+	info.MemFree = info.Fields["MemFree"]
+	info.MemAvailable = info.Fields["MemAvailable"]
+	info.Buffers = info.Fields["Buffers"]
+	info.Cached = info.Fields["Cached"]
+	info.SwapTotal = info.Fields["SwapTotal"]
+	info.SwapFree = info.Fields["SwapFree"]
+
+	return info, nil
+}
+
+// CPUTimes is the time a CPU spent in each mode, in clock ticks
+type CPUTimes struct {
+	User      uint64
+	Nice      uint64
+	System    uint64
+	Idle      uint64
+	IOWait    uint64
+	IRQ       uint64
+	SoftIRQ   uint64
+	Steal     uint64
+	Guest     uint64
+	GuestNice uint64
+}
+
+// Total gets the time the CPU has spent in all modes. Guest time is already
+// counted in user time, so it isn't added again
+func (times CPUTimes) Total() uint64 {
+	return times.User + times.Nice + times.System + times.Idle +
+		times.IOWait + times.IRQ + times.SoftIRQ + times.Steal
+}
+
+// Busy gets the time the CPU has spent doing work, which is all the time it
+// wasn't idle or waiting for IO
+func (times CPUTimes) Busy() uint64 {
+	return times.Total() - times.Idle - times.IOWait
+}
+
+// SystemStat is the system activity from /proc/stat
+type SystemStat struct {
+	// CPU is the times of all the CPUs added together
+	CPU CPUTimes
+	// CPUs are the times of each CPU, by their number. CPUs that are offline
+	// are not included
+	CPUs            map[int]CPUTimes
+	BootTime        int64
+	ContextSwitches uint64
+	Processes       uint64
+	ProcsRunning    uint64
+	ProcsBlocked    uint64
+}
+
+// Stat gets the system activity
+func (fs FS) Stat() (SystemStat, error) {
+	var content, err = fs.readString("stat")
+	if err != nil {
+		return SystemStat{}, err
+	}
+	return parseSystemStat(fs.Path("stat"), content)
+}
+
+// parseSystemStat parses the contents of a stat file
+func parseSystemStat(file string, content string) (SystemStat, error) {
+	var stat = SystemStat{CPUs: make(map[int]CPUTimes)}
+
+	for _, line := range strings.Split(content, "\n") {
+		var fields = strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		var err error
+		switch {
+		case fields[0] == "cpu":
+			stat.CPU, err = parseCPUTimes(file, fields[1:])
+		case strings.HasPrefix(fields[0], "cpu"):
+			var cpu int64
+			if cpu, err = parseInt(file, strings.TrimPrefix(fields[0], "cpu")); err == nil {
+				stat.CPUs[int(cpu)], err = parseCPUTimes(file, fields[1:])
+			}
+		case fields[0] == "btime":
+			stat.BootTime, err = parseInt(file, fields[1])
+		case fields[0] == "ctxt":
+			stat.ContextSwitches, err = parseUint(file, fields[1])
+		case fields[0] == "processes":
+			stat.Processes, err = parseUint(file, fields[1])
+		case fields[0] == "procs_running":
+			stat.ProcsRunning, err = parseUint(file, fields[1])
+		case fields[0] == "procs_blocked":
+			stat.ProcsBlocked, err = parseUint(file, fields[1])
+		}
+		if err != nil {
+			return stat, err
+		}
+	}
+
+	return stat, nil
+}
+
+// parseCPUTimes parses the times of a cpu line. Older kernels have fewer
+// times, which are left as 0
+func parseCPUTimes(file string, fields []string) (CPUTimes, error) {
+	var times CPUTimes
+	var columns = []*uint64{&times.User, &times.Nice, &times.System,
+		&times.Idle, &times.IOWait, &times.IRQ, &times.SoftIRQ, &times.Steal,
+		&times.Guest, &times.GuestNice}
+
+	for i, field := range fields {
+		if i >= len(columns) {
+			break
+		}
+
+		var value, err = parseUint(file, field)
+		if err != nil {
+			return times, err
+		}
+		*columns[i] = value
+	}
+	return times, nil
+}
+
+// VMStat gets the virtual memory counters from /proc/vmstat, by name
+func (fs FS) VMStat() (map[string]uint64, error) {
+	var content, err = fs.readString("vmstat")
+	if err != nil {
+		return nil, err
+	}
+
+	var counters = make(map[string]uint64)
+	for _, line := range strings.Split(content, "\n") {
+		var fields = strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		var value, parseErr = parseUint(fs.Path("vmstat"), fields[1])
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		counters[fields[0]] = value
+	}
+	return counters, nil
+}
+
+// BootID gets the random id the kernel generates each time it boots
+func (fs FS) BootID() (string, error) {
+	var content, err = fs.readString("sys", "kernel", "random", "boot_id")
+	return strings.TrimSpace(content), err
+}
@@ -0,0 +1,149 @@
+package procfs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadAvg(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"loadavg": "0.52 1.25 2.00 3/512 12345\n",
+		"uptime":  "3600.50 7000.25\n",
+	})
+
+	var load, err = fs.LoadAvg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = LoadAvg{Load1: 0.52, Load5: 1.25, Load15: 2, Running: 3,
+		Total: 512, LastPID: 12345}
+	if load != expected {
+		t.Errorf("expected %+v, got %+v", expected, load)
+	}
+
+	var uptime float64
+	if uptime, err = fs.Uptime(); err != nil || uptime != 3600.5 {
+		t.Errorf("expected uptime 3600.5, got %f %v", uptime, err)
+	}
+
+	if _, err = parseLoadAvg("loadavg", "0.52 1.25 2.00 3 12345\n"); err == nil {
+		t.Error("expected invalid tasks to be an error")
+	}
+}
+
+func TestMemInfo(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"meminfo": "MemTotal:        8000000 kB\n" +
+			"MemFree:         1000000 kB\n" +
+			"MemAvailable:    4000000 kB\n" +
+			"Buffers:          200000 kB\n" +
+			"Cached:          2000000 kB\n" +
+			"SwapTotal:       1000000 kB\n" +
+			"SwapFree:         900000 kB\n" +
+			"HugePages_Total:       4\n",
+	})
+
+	var info, err = fs.MemInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MemTotal != 8000000*1024 || info.MemFree != 1000000*1024 ||
+		info.MemAvailable != 4000000*1024 || info.Buffers != 200000*1024 ||
+		info.Cached != 2000000*1024 || info.SwapTotal != 1000000*1024 ||
+		info.SwapFree != 900000*1024 {
+		t.Errorf("expected the sizes in bytes, got %+v", info)
+	}
+	// Counts are not sizes, so are not converted
+	if info.Fields["HugePages_Total"] != 4 {
+		t.Errorf("expected 4 huge pages, got %d", info.Fields["HugePages_Total"])
+	}
+
+	if _, err = parseMemInfo("meminfo", "MemFree: 1000 kB\n"); err == nil {
+		t.Error("expected no MemTotal to be an error")
+	}
+}
+
+func TestSystemStat(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"stat": "cpu  100 5 50 1000 20 1 2 3 10 0\n" +
+			"cpu0 60 5 30 400 10 1 1 2 10 0\n" +
+			"cpu2 40 0 20 600 10 0 1 1\n" +
+			"intr 12345 1 2 3\n" +
+			"ctxt 987654\n" +
+			"btime 1700000000\n" +
+			"processes 4321\n" +
+			"procs_running 2\n" +
+			"procs_blocked 1\n",
+	})
+
+	var stat, err = fs.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cpu = CPUTimes{User: 100, Nice: 5, System: 50, Idle: 1000, IOWait: 20,
+		IRQ: 1, SoftIRQ: 2, Steal: 3, Guest: 10}
+	if stat.CPU != cpu {
+		t.Errorf("expected %+v, got %+v", cpu, stat.CPU)
+	}
+	if stat.CPU.Total() != 1181 || stat.CPU.Busy() != 161 {
+		t.Errorf("expected total 1181 and busy 161, got %d %d",
+			stat.CPU.Total(), stat.CPU.Busy())
+	}
+
+	// Offline CPUs are not included, and older kernels have fewer times
+	var cpu2 = CPUTimes{User: 40, System: 20, Idle: 600, IOWait: 10,
+		SoftIRQ: 1, Steal: 1}
+	if len(stat.CPUs) != 2 || stat.CPUs[2] != cpu2 {
+		t.Errorf("expected cpus 0 and 2, got %+v", stat.CPUs)
+	}
+
+	if stat.BootTime != 1700000000 || stat.ContextSwitches != 987654 ||
+		stat.Processes != 4321 || stat.ProcsRunning != 2 || stat.ProcsBlocked != 1 {
+		t.Errorf("expected the counters, got %+v", stat)
+	}
+}
+
+func TestVMStat(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"vmstat": "pgfault 1000\npgmajfault 20\noom_kill 1\n",
+	})
+
+	var counters, err = fs.VMStat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = map[string]uint64{"pgfault": 1000, "pgmajfault": 20, "oom_kill": 1}
+	if !reflect.DeepEqual(counters, expected) {
+		t.Errorf("expected %v, got %v", expected, counters)
+	}
+}
+
+func TestPressure(t *testing.T) {
+	var fs = fixture(t, map[string]string{
+		"pressure/memory": "some avg10=1.50 avg60=0.75 avg300=0.25 total=123456\n" +
+			"full avg10=0.50 avg60=0.00 avg300=0.10 total=654\n",
+		"pressure/cpu": "some avg10=10.00 avg60=5.00 avg300=1.00 total=999\n",
+		"pressure/io":  "most avg10=0.00\n",
+	})
+
+	var pressure, err = fs.Pressure(PressureMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = Pressure{
+		Some: PressureStats{Avg10: 1.5, Avg60: 0.75, Avg300: 0.25, Total: 123456},
+		Full: PressureStats{Avg10: 0.5, Avg60: 0, Avg300: 0.1, Total: 654},
+	}
+	if pressure != expected {
+		t.Errorf("expected %+v, got %+v", expected, pressure)
+	}
+
+	// The cpu file had no full line before Linux 5.13
+	if pressure, err = fs.Pressure(PressureCPU); err != nil ||
+		pressure.Some.Total != 999 || pressure.Full != (PressureStats{}) {
+		t.Errorf("expected only some cpu pressure, got %+v %v", pressure, err)
+	}
+	if _, err = fs.Pressure(PressureIO); err == nil {
+		t.Error("expected an unknown line to be an error")
+	}
+}
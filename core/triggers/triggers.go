@@ -21,6 +21,29 @@ func NewFuncTrigger(onEvent func(*core.WatchEvent)) core.WatchTrigger {
 	return trigger
 }
 
+// FuncErrorTrigger calls a function when a WatchEvent triggers and another
+// when a watch fails to observe
+type FuncErrorTrigger struct {
+	FuncTrigger
+	onError func(error)
+}
+
+// OnError is called when a watch fails to observe
+func (trigger FuncErrorTrigger) OnError(err error) {
+	go func() {
+		trigger.onError(err)
+	}()
+}
+
+// NewFuncErrorTrigger create a new FuncErrorTrigger for the provided funcs
+func NewFuncErrorTrigger(onEvent func(*core.WatchEvent),
+	onError func(error)) core.WatchErrorTrigger {
+	trigger := new(FuncErrorTrigger)
+	trigger.onEvent = onEvent
+	trigger.onError = onError
+	return trigger
+}
+
 // BroadcastTrigger sends a WatchEvent to all triggers attaches to this trigger
 // when a WatchEvent is triggered
 type BroadcastTrigger struct {
@@ -37,6 +60,18 @@ func (bt BroadcastTrigger) OnEvent(e *core.WatchEvent) {
 	}
 }
 
+// OnError sends the error to all attached triggers that handle errors
+func (bt BroadcastTrigger) OnError(err error) {
+	for t := range bt.triggers {
+		trigger, ok := bt.triggers[t].(core.WatchErrorTrigger)
+		if ok {
+			go func() {
+				trigger.OnError(err)
+			}()
+		}
+	}
+}
+
 // NewBroadcastTrigger creates a new BroadcastTrigger for the provided triggers
 func NewBroadcastTrigger(triggers []core.WatchTrigger) core.WatchTrigger {
 	bt := new(BroadcastTrigger)
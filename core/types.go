@@ -2,6 +2,9 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strconv"
 	"time"
 )
@@ -77,6 +80,13 @@ type WatchTrigger interface {
 	OnEvent(*WatchEvent)
 }
 
+// WatchErrorTrigger is a WatchTrigger that can also be told when a watch fails
+// to observe
+type WatchErrorTrigger interface {
+	WatchTrigger
+	OnError(error)
+}
+
 // WatcherCanceller can cancel a Watcher
 type WatcherCanceller func()
 
@@ -85,7 +95,73 @@ type Watcher interface {
 	Watch(WatchTrigger) WatcherCanceller
 }
 
+// ContextWatcher is a Watcher that stops watching when the provided context is
+// done
+type ContextWatcher interface {
+	Watcher
+	WatchContext(context.Context, WatchTrigger) WatcherCanceller
+}
+
 // Watch is an interface for something that can be watched
 type Watch interface {
 	Observe() *WatchEvent
 }
+
+// ContextWatch is a Watch that can be observed with a context, returning an
+// error if it could not be observed. A nil event and nil error means nothing
+// has changed
+type ContextWatch interface {
+	ObserveContext(context.Context) (*WatchEvent, error)
+}
+
+// MultiWatch is a ContextWatch that can have several events ready each time
+// it is observed. Watchers use ObserveAll to get all of them
+type MultiWatch interface {
+	ContextWatch
+	ObserveAll(context.Context) ([]*WatchEvent, error)
+}
+
+// ErrWatchStopped is returned when a watch can no longer be observed
+var ErrWatchStopped = errors.New("watch stopped")
+
+// WatchError is an error returned when a watch fails to observe
+type WatchError struct {
+	Watch string
+	Err   error
+}
+
+func (e *WatchError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Watch, e.Err)
+}
+
+// Unwrap gets the underlying error
+func (e *WatchError) Unwrap() error {
+	return e.Err
+}
+
+// NewWatchError creates a new WatchError for the named watch
+func NewWatchError(watch string, err error) *WatchError {
+	return &WatchError{Watch: watch, Err: err}
+}
+
+// watchAdapter adapts a Watch so that it can be used as a ContextWatch
+type watchAdapter struct {
+	watch Watch
+}
+
+// ObserveContext observes the adapted watch unless the context is done
+func (a watchAdapter) ObserveContext(ctx context.Context) (*WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.watch.Observe(), nil
+}
+
+// ContextWatchFor gets a ContextWatch for the provided watch, using the watch
+// itself if it is already a ContextWatch
+func ContextWatchFor(watch Watch) ContextWatch {
+	if cw, ok := watch.(ContextWatch); ok {
+		return cw
+	}
+	return watchAdapter{watch}
+}
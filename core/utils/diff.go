@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+var diffContext = 3
+
+// maxDiffCells limits the size of the table used to compare texts
+var maxDiffCells = 4 * 1024 * 1024
+
+// diffOp is a line in a diff, with a kind of ' ', '-' or '+'. The line keeps
+// its line ending, so a last line without one differs from the same line with
+// one
+type diffOp struct {
+	kind    byte
+	line    string
+	oldLine int
+	newLine int
+}
+
+// UnifiedDiff gets a unified diff between two texts, or an empty string if
+// they are the same or too large to compare
+func UnifiedDiff(oldName string, newName string, oldText string,
+	newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	var oldLines = splitLines(oldText)
+	var newLines = splitLines(newText)
+
+	if (len(oldLines)+1)*(len(newLines)+1) > maxDiffCells {
+		log.Debug("Texts are too large to diff")
+		return ""
+	}
+
+	var ops = diffLines(oldLines, newLines)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Group the changes into hunks with context around them
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		var first = start - diffContext
+		if first < 0 {
+			first = 0
+		}
+
+		var end = start
+		for unchanged := 0; end < len(ops) && unchanged <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+
+		// Trim trailing context down to the limit
+		var last = end
+		for last > start && ops[last-1].kind == ' ' {
+			last--
+		}
+		last += diffContext
+		if last > len(ops) {
+			last = len(ops)
+		}
+
+		writeHunk(&out, ops[first:last])
+		start = last
+	}
+
+	return out.String()
+}
+
+// writeHunk writes a hunk header and its lines
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	var oldStart, newStart, oldCount, newCount = -1, -1, 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+			if oldStart < 0 {
+				oldStart = op.oldLine
+			}
+		}
+		if op.kind != '-' {
+			newCount++
+			if newStart < 0 {
+				newStart = op.newLine
+			}
+		}
+	}
+
+	// Empty ranges start at the line before them
+	if oldStart < 0 {
+		oldStart = ops[0].oldLine - 1
+	}
+	if newStart < 0 {
+		newStart = ops[0].newLine - 1
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart,
+		newCount)
+	for _, op := range ops {
+		fmt.Fprintf(out, "%c%s", op.kind, op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// diffLines finds the changes between two sets of lines using their longest
+// common subsequence
+func diffLines(oldLines []string, newLines []string) []diffOp {
+	var n, m = len(oldLines), len(newLines)
+
+	// lcs[i][j] is the length of the common subsequence of the lines after i
+	// and j
+	var lcs = make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	var i, j = 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{' ', oldLines[i], i + 1, j + 1})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', oldLines[i], i + 1, j + 1})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', newLines[j], i + 1, j + 1})
+			j++
+		}
+	}
+	return ops
+}
+
+// splitLines splits text into lines, keeping their line endings
+func splitLines(text string) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	var lines = strings.SplitAfter(text, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	var lines = func(from int, to int) string {
+		var text strings.Builder
+		for i := from; i <= to; i++ {
+			text.WriteString(string(rune('a'+i-1)) + "\n")
+		}
+		return text.String()
+	}
+
+	for _, test := range []struct {
+		name     string
+		oldText  string
+		newText  string
+		expected string
+	}{
+		{"same", "a\nb\n", "a\nb\n", ""},
+		{"changed", "a\nb\nc\n", "a\nB\nc\n",
+			"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"added to empty", "", "a\nb\n",
+			"@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"removed all", "a\n", "",
+			"@@ -1,1 +0,0 @@\n-a\n"},
+		{"context", lines(1, 10), strings.Replace(lines(1, 10), "e\n", "E\n", 1),
+			"@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n"},
+		{"separate hunks", lines(1, 20),
+			strings.Replace(strings.Replace(lines(1, 20), "b\n", "B\n", 1),
+				"s\n", "S\n", 1),
+			"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -16,5 +16,5 @@\n p\n q\n r\n-s\n+S\n t\n"},
+		{"newline added", "a\nb", "a\nb\n",
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"},
+		{"newline removed", "a\nb\n", "a\nb",
+			"@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n"},
+		{"changed without newline", "a", "b",
+			"@@ -1,1 +1,1 @@\n-a\n\\ No newline at end of file\n+b\n" +
+				"\\ No newline at end of file\n"},
+	} {
+		var diff = UnifiedDiff("old", "new", test.oldText, test.newText)
+		var expected = test.expected
+		if len(expected) > 0 {
+			expected = "--- old\n+++ new\n" + expected
+		}
+		if diff != expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.name, expected, diff)
+		}
+	}
+}
+
+func TestUnifiedDiffTooLarge(t *testing.T) {
+	var cells = maxDiffCells
+	maxDiffCells = 10
+	defer func() { maxDiffCells = cells }()
+
+	if diff := UnifiedDiff("old", "new", "a\nb\nc\n", "a\nb\nd\n"); diff != "" {
+		t.Errorf("expected no diff for large texts, got %q", diff)
+	}
+}
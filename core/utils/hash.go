@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"unicode/utf8"
+)
+
+// HashContent reads up to maxBytes (or everything if maxBytes <= 0) from the
+// reader, returning the hex encoded SHA-256 digest of what was read and the
+// content itself
+func HashContent(reader io.Reader, maxBytes int64) (string, []byte, error) {
+	if maxBytes > 0 {
+		reader = io.LimitReader(reader, maxBytes)
+	}
+
+	var content, err = ioutil.ReadAll(reader)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sum = sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), content, nil
+}
+
+// IsText returns true if the content looks like text rather than binary data
+func IsText(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
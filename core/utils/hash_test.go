@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashContent(t *testing.T) {
+	for _, test := range []struct {
+		content  string
+		maxBytes int64
+		expected string
+		digest   string
+	}{
+		{"", 0, "",
+			"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"hello\n", 0, "hello\n",
+			"5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{"hello\nworld\n", 6, "hello\n",
+			"5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{"hello\n", 100, "hello\n",
+			"5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+	} {
+		var digest, content, err = HashContent(strings.NewReader(test.content),
+			test.maxBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != test.expected || digest != test.digest {
+			t.Errorf("%q limited to %d: expected %q %s, got %q %s", test.content,
+				test.maxBytes, test.expected, test.digest, content, digest)
+		}
+	}
+}
+
+func TestIsText(t *testing.T) {
+	for content, expected := range map[string]bool{
+		"":             true,
+		"hello\n":      true,
+		"café":         true,
+		"a\x00b":       false,
+		"\xff\xfe\x00": false,
+	} {
+		if IsText([]byte(content)) != expected {
+			t.Errorf("expected IsText(%q) to be %v", content, expected)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPathLookup gets the value at a simple JSONPath within a decoded JSON
+// document. Paths like $.items[0].name and $['key'] are supported
+func JSONPathLookup(doc interface{}, path string) (interface{}, error) {
+	var rest = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var value = doc
+
+	for len(rest) > 0 {
+		var key string
+		var index = -1
+
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			var end = strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key, rest = rest[:end], rest[end:]
+		case '[':
+			var end = strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in path %s", path)
+			}
+			var selector = strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			if strings.HasPrefix(selector, "'") || strings.HasPrefix(selector, "\"") {
+				key = strings.Trim(selector, "'\"")
+			} else {
+				var i, err = strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %s in path %s", selector,
+						path)
+				}
+				index = i
+			}
+		default:
+			return nil, fmt.Errorf("invalid path %s", path)
+		}
+
+		if index >= 0 {
+			var array, ok = value.([]interface{})
+			if !ok || index >= len(array) {
+				return nil, fmt.Errorf("no index %d in path %s", index, path)
+			}
+			value = array[index]
+		} else {
+			var object, ok = value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("no key %s in path %s", key, path)
+			}
+			if value, ok = object[key]; !ok {
+				return nil, fmt.Errorf("no key %s in path %s", key, path)
+			}
+		}
+	}
+
+	return value, nil
+}
@@ -4,6 +4,8 @@ import (
 	"container/list"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 var log = GetLogger()
@@ -21,6 +23,40 @@ func PathExists(path string) bool {
 	return false
 }
 
+// MatchAll is a FilterFunc that matches everything
+func MatchAll(string) bool {
+	return true
+}
+
+// GlobFilter creates a FilterFunc that matches names matching any of the
+// include glob patterns (or all names if there are none) and none of the
+// exclude glob patterns
+func GlobFilter(include []string, exclude []string) FilterFunc {
+	return func(name string) bool {
+		for _, pattern := range exclude {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return false
+			}
+		}
+
+		if len(include) == 0 {
+			return true
+		}
+
+		for _, pattern := range include {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasGlob returns true if the provided path contains glob meta characters
+func HasGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
 // FilesInDir gets a List of files in the provided directory that match the
 // provided FilterFunc
 func FilesInDir(dir string, filter FilterFunc) *list.List {
@@ -55,3 +91,28 @@ func GetFileAsString(path string) (string, error) {
 	str := string(bytes)
 	return str, nil
 }
+
+// WriteFileAtomic writes data to a file by writing a temporary file next to it
+// and renaming that over it, so the file is never left partly written
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
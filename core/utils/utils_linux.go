@@ -2,33 +2,40 @@ package utils
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"syscall"
+
+	"github.com/deanydean/clockwork/core/procfs"
 )
 
 // ProcessExists returns true if a process identified by pid exists, false if
 // not
 func ProcessExists(pid int) bool {
-	return PathExists("/proc/" + strconv.Itoa(pid))
+	return procfs.Default.Proc(pid).Exists()
 }
 
 // GetProcessStats returns the process stats information for the provided pid
 // and an error which is set if something went wrong
-func GetProcessStats(pid int) (string, error) {
-	var statFile = "/proc/" + strconv.Itoa(pid) + "/stat"
-	return GetFileAsString(statFile)
+func GetProcessStats(pid int) (procfs.ProcStat, error) {
+	return procfs.Default.Proc(pid).Stat()
 }
 
-func GetProcessIO(pid int) (string, error) {
-	var ioFile = "/proc/" + strconv.Itoa(pid) + "/io"
-	return GetFileAsString(ioFile)
+// GetProcessIO returns the IO counters for the provided pid and an error which
+// is set if something went wrong
+func GetProcessIO(pid int) (procfs.ProcIO, error) {
+	return procfs.Default.Proc(pid).IO()
 }
 
+// GetSystemUptime returns the number of seconds since the system booted, or 0
+// if it is not known
 func GetSystemUptime() float64 {
-	var statFile = "/proc/uptime"
-	var fileContents, _ = GetFileAsString(statFile)
-	var uptime, _ = strconv.ParseFloat(strings.Split(fileContents, " ")[0], 64)
+	var uptime, err = procfs.Default.Uptime()
+	if err != nil {
+		log.Warn("Failed to read uptime : %s", err)
+	}
 	return uptime
 }
 
@@ -71,3 +78,11 @@ func GetPageSize() int {
 	fmt.Println("Failed to get system page size err=", err)
 	return -1
 }
+
+// FileInode gets the inode number of a file, or 0 if it is not known
+func FileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Ino
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package watchers
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for time to pass
+type Clock interface {
+	Now() time.Time
+	After(time.Duration) <-chan time.Time
+}
+
+// systemClock is a Clock that uses the system time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemClock is the Clock used by watchers unless another is provided
+var SystemClock Clock = systemClock{}
+
+// ManualClock is a Clock that only moves when it is told to, so watchers can
+// be driven deterministically
+type ManualClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []manualWaiter
+}
+
+// manualWaiter is waiting for a ManualClock to reach a time
+type manualWaiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewManualClock creates a ManualClock set to the provided time
+func NewManualClock(now time.Time) *ManualClock {
+	clock := new(ManualClock)
+	clock.now = now
+	return clock
+}
+
+// Now gets the current time of the clock
+func (clock *ManualClock) Now() time.Time {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	return clock.now
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by at least d
+func (clock *ManualClock) After(d time.Duration) <-chan time.Time {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- clock.now
+		return ch
+	}
+
+	clock.waiters = append(clock.waiters, manualWaiter{clock.now.Add(d), ch})
+	return ch
+}
+
+// Advance moves the clock forward, waking anything waiting for the new time
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+
+	clock.now = clock.now.Add(d)
+
+	var waiting = clock.waiters[:0]
+	for _, waiter := range clock.waiters {
+		if waiter.until.After(clock.now) {
+			waiting = append(waiting, waiter)
+		} else {
+			waiter.ch <- clock.now
+		}
+	}
+	clock.waiters = waiting
+}
@@ -0,0 +1,398 @@
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// cronField is a set of allowed values for a cron field
+type cronField uint64
+
+func (f cronField) has(value int) bool {
+	return f&(1<<uint(value)) != 0
+}
+
+// cronBounds are the limits and names of a cron field
+type cronBounds struct {
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronSeconds = cronBounds{0, 59, nil}
+var cronMinutes = cronBounds{0, 59, nil}
+var cronHours = cronBounds{0, 23, nil}
+var cronDaysOfMonth = cronBounds{1, 31, nil}
+var cronMonths = cronBounds{1, 12, map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}}
+var cronDaysOfWeek = cronBounds{0, 7, map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}}
+
+// cronDescriptors are the predefined schedules that can replace the fields
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// CronSchedule is a schedule described by a cron expression
+type CronSchedule struct {
+	expr       string
+	second     cronField
+	minute     cronField
+	hour       cronField
+	dayOfMonth cronField
+	month      cronField
+	dayOfWeek  cronField
+	anyDay     bool
+	anyWeekday bool
+	location   *time.Location
+}
+
+// ParseCron parses a standard 5 field cron expression, or a 6 field expression
+// with a leading seconds field. The expression can be prefixed with
+// CRON_TZ=<zone> or TZ=<zone> to use a timezone other than local time
+func ParseCron(expr string) (*CronSchedule, error) {
+	var location = time.Local
+	var spec = strings.TrimSpace(expr)
+
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		var parts = strings.SplitN(spec, " ", 2)
+		var zone = parts[0][strings.Index(parts[0], "=")+1:]
+
+		var loc, err = time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron timezone %s: %s", zone, err)
+		}
+		location = loc
+
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("missing cron fields in %q", expr)
+		}
+		spec = strings.TrimSpace(parts[1])
+	}
+
+	return ParseCronIn(spec, location)
+}
+
+// ParseCronIn parses a cron expression that will be evaluated in the provided
+// location
+func ParseCronIn(expr string, location *time.Location) (*CronSchedule, error) {
+	var spec = strings.TrimSpace(expr)
+	if descriptor, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+
+	var fields = strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron expression %q must have 5 or 6 fields",
+			expr)
+	}
+
+	var schedule = new(CronSchedule)
+	schedule.expr = expr
+	schedule.location = location
+
+	var err error
+	if schedule.second, err = parseCronField(fields[0], cronSeconds); err != nil {
+		return nil, err
+	}
+	if schedule.minute, err = parseCronField(fields[1], cronMinutes); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = parseCronField(fields[2], cronHours); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfMonth, err = parseCronField(fields[3],
+		cronDaysOfMonth); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = parseCronField(fields[4], cronMonths); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfWeek, err = parseCronField(fields[5],
+		cronDaysOfWeek); err != nil {
+		return nil, err
+	}
+
+	// Sunday can be 0 or 7
+	if schedule.dayOfWeek.has(7) {
+		schedule.dayOfWeek |= 1
+	}
+
+	schedule.anyDay = isCronWildcard(fields[3])
+	schedule.anyWeekday = isCronWildcard(fields[5])
+
+	return schedule, nil
+}
+
+func isCronWildcard(field string) bool {
+	return strings.HasPrefix(field, "*") || strings.HasPrefix(field, "?")
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+func parseCronField(field string, bounds cronBounds) (cronField, error) {
+	var result cronField
+
+	for _, part := range strings.Split(field, ",") {
+		var step = 1
+		var rangeStr = part
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			var value, err = strconv.Atoi(part[i+1:])
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("invalid cron step in %q", part)
+			}
+			step = value
+			rangeStr = part[:i]
+		}
+
+		var start, end int
+		if rangeStr == "*" || rangeStr == "?" {
+			start, end = bounds.min, bounds.max
+		} else if i := strings.Index(rangeStr, "-"); i >= 0 {
+			var err error
+			if start, err = parseCronValue(rangeStr[:i], bounds); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(rangeStr[i+1:], bounds); err != nil {
+				return 0, err
+			}
+		} else {
+			var err error
+			if start, err = parseCronValue(rangeStr, bounds); err != nil {
+				return 0, err
+			}
+			end = start
+			if step > 1 {
+				end = bounds.max
+			}
+		}
+
+		if start > end {
+			return 0, fmt.Errorf("invalid cron range %q", part)
+		}
+
+		for value := start; value <= end; value += step {
+			result |= 1 << uint(value)
+		}
+	}
+
+	return result, nil
+}
+
+// parseCronValue parses a number or name within the bounds of a field
+func parseCronValue(value string, bounds cronBounds) (int, error) {
+	if named, ok := bounds.names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+
+	var number, err = strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cron value %q", value)
+	}
+	if number < bounds.min || number > bounds.max {
+		return 0, fmt.Errorf("cron value %d out of range %d-%d", number,
+			bounds.min, bounds.max)
+	}
+	return number, nil
+}
+
+// Next gets the first time after t that matches the schedule, or the zero time
+// if nothing matches within five years. Like cron, times skipped by a daylight
+// saving change are not run and times repeated by one are only run once
+func (schedule *CronSchedule) Next(t time.Time) time.Time {
+	var loc = schedule.location
+	t = t.In(loc).Truncate(time.Second).Add(time.Second)
+	var yearLimit = t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for !schedule.month.has(int(t.Month())) {
+		t = later(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !schedule.dayMatches(t) {
+		t = later(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for !schedule.hour.has(t.Hour()) {
+		t = later(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for !schedule.minute.has(t.Minute()) {
+		t = t.Truncate(time.Minute).Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for !schedule.second.has(t.Second()) {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	if repeated(t) {
+		return schedule.Next(t)
+	}
+	return t
+}
+
+// later gets next, stepped forward until it is after t. A wall clock time in
+// a daylight saving gap is normalised to before the gap, which could otherwise
+// leave Next stuck on the same time
+func later(t time.Time, next time.Time) time.Time {
+	for !next.After(t) {
+		next = next.Add(time.Hour)
+	}
+	return next
+}
+
+// repeated checks if the wall clock time of t has already happened earlier,
+// because the clocks went back
+func repeated(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-3 * time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+
+	var first = t.Add(-time.Duration(before-offset) * time.Second)
+	_, firstOffset := first.Zone()
+	return firstOffset == before && first.Day() == t.Day() &&
+		first.Hour() == t.Hour() && first.Minute() == t.Minute() &&
+		first.Second() == t.Second()
+}
+
+// dayMatches checks the day of month and day of week fields. As with cron, if
+// both are restricted then a match on either is enough
+func (schedule *CronSchedule) dayMatches(t time.Time) bool {
+	var dom = schedule.dayOfMonth.has(t.Day())
+	var dow = schedule.dayOfWeek.has(int(t.Weekday()))
+
+	if schedule.anyDay || schedule.anyWeekday {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (schedule *CronSchedule) String() string {
+	return schedule.expr
+}
+
+// CronWatcher is a Watcher that observes a Watch at the times described by a
+// CronSchedule
+type CronWatcher struct {
+	watch    core.Watch
+	schedule *CronSchedule
+	clock    Clock
+	stopper  chan bool
+}
+
+// NewCronWatcher creates a new CronWatcher for the provided watch and schedule
+func NewCronWatcher(watch core.Watch, schedule *CronSchedule) *CronWatcher {
+	return NewCronWatcherWithClock(watch, schedule, SystemClock)
+}
+
+// NewCronWatcherWithClock creates a new CronWatcher that uses the provided
+// clock to decide when the watch is due
+func NewCronWatcherWithClock(watch core.Watch, schedule *CronSchedule,
+	clock Clock) *CronWatcher {
+	cw := new(CronWatcher)
+	cw.watch = watch
+	cw.schedule = schedule
+	cw.clock = clock
+	cw.stopper = make(chan bool, 1)
+	return cw
+}
+
+// Watch the Watch at each time in the schedule
+func (cw CronWatcher) Watch(trigger core.WatchTrigger) core.WatcherCanceller {
+	return cw.WatchContext(context.Background(), trigger)
+}
+
+// WatchContext watches the Watch at each time in the schedule until the
+// context is done or the watcher is stopped
+func (cw CronWatcher) WatchContext(ctx context.Context,
+	trigger core.WatchTrigger) core.WatcherCanceller {
+	var watch = core.ContextWatchFor(cw.watch)
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer cancel()
+
+		for {
+			var now = cw.clock.Now()
+			var next = cw.schedule.Next(now)
+			if next.IsZero() {
+				log.Warn("Cron schedule %s will never run", cw.schedule)
+				return
+			}
+			log.Debug("Next run of watch=%s at %s", cw.watch, next)
+
+			select {
+			case <-ctx.Done():
+				return
+			case stopSignal := <-cw.stopper:
+				if stopSignal {
+					return
+				}
+				continue
+			case <-cw.clock.After(next.Sub(now)):
+			}
+
+			results, err := observeEvents(ctx, watch)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				notifyError(trigger, err)
+			}
+			for _, result := range results {
+				if result.ShouldStop() {
+					log.Warn("Watch needs to stop")
+					return
+				}
+				trigger.OnEvent(result)
+			}
+		}
+	}()
+
+	return cw.Stop
+}
+
+// Stop the CronWatcher
+func (cw CronWatcher) Stop() {
+	cw.stopper <- true
+}
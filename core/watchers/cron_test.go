@@ -0,0 +1,157 @@
+package watchers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// clockWatch is a watch that reports the time on a clock each time it is
+// observed
+type clockWatch struct {
+	clock    Clock
+	observed chan time.Time
+}
+
+func (watch *clockWatch) Observe() *core.WatchEvent {
+	watch.observed <- watch.clock.Now()
+	return nil
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %s", name, err)
+	}
+	return loc
+}
+
+func checkNext(t *testing.T, schedule *CronSchedule, from time.Time,
+	expected ...time.Time) {
+	t.Helper()
+
+	var next = from
+	for _, want := range expected {
+		next = schedule.Next(next)
+		if !next.Equal(want) {
+			t.Fatalf("%s: expected %s, got %s", schedule, want, next)
+		}
+	}
+}
+
+func TestCronNextSkipsShortMonths(t *testing.T) {
+	schedule, err := ParseCronIn("0 0 31 * *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkNext(t, schedule, time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 7, 31, 0, 0, 0, 0, time.UTC))
+}
+
+func TestCronNextLeapDay(t *testing.T) {
+	schedule, err := ParseCron("0 12 29 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loc = schedule.location
+
+	checkNext(t, schedule, time.Date(2023, 3, 1, 0, 0, 0, 0, loc),
+		time.Date(2024, 2, 29, 12, 0, 0, 0, loc),
+		time.Date(2028, 2, 29, 12, 0, 0, 0, loc))
+}
+
+func TestCronNextSpringForward(t *testing.T) {
+	var loc = mustLoadLocation(t, "America/New_York")
+	schedule, err := ParseCronIn("30 2 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 02:30 does not exist on 2024-03-10
+	checkNext(t, schedule, time.Date(2024, 3, 9, 3, 0, 0, 0, loc),
+		time.Date(2024, 3, 11, 2, 30, 0, 0, loc),
+		time.Date(2024, 3, 12, 2, 30, 0, 0, loc))
+
+	hourly, err := ParseCronIn("0 * * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNext(t, hourly, time.Date(2024, 3, 10, 0, 30, 0, 0, loc),
+		time.Date(2024, 3, 10, 1, 0, 0, 0, loc),
+		time.Date(2024, 3, 10, 3, 0, 0, 0, loc),
+		time.Date(2024, 3, 10, 4, 0, 0, 0, loc))
+}
+
+func TestCronNextFallBack(t *testing.T) {
+	var loc = mustLoadLocation(t, "America/New_York")
+	schedule, err := ParseCronIn("30 1 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 01:30 happens twice on 2024-11-03 but should only run once
+	var first = time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC)
+	checkNext(t, schedule, time.Date(2024, 11, 2, 3, 0, 0, 0, loc),
+		first.In(loc),
+		time.Date(2024, 11, 4, 1, 30, 0, 0, loc))
+}
+
+// waitForWaiter blocks until something is waiting on the clock
+func waitForWaiter(t *testing.T, clock *ManualClock) {
+	t.Helper()
+
+	var deadline = time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		clock.lock.Lock()
+		var waiting = len(clock.waiters)
+		clock.lock.Unlock()
+		if waiting > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the watcher to wait on the clock")
+}
+
+func TestCronWatcherFiresOnSchedule(t *testing.T) {
+	schedule, err := ParseCronIn("0 0 31 * *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var clock = NewManualClock(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC))
+	var watch = &clockWatch{clock: clock, observed: make(chan time.Time, 1)}
+	var watcher = NewCronWatcherWithClock(watch, schedule, clock)
+	defer watcher.Watch(nil)()
+
+	var expected = []time.Time{
+		time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC),
+	}
+	for _, want := range expected {
+		waitForWaiter(t, clock)
+
+		// Nothing should fire until the clock reaches the next run
+		clock.Advance(want.Sub(clock.Now()) - time.Second)
+		select {
+		case at := <-watch.observed:
+			t.Fatalf("watch observed early at %s", at)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		waitForWaiter(t, clock)
+		clock.Advance(time.Second)
+		select {
+		case at := <-watch.observed:
+			if !at.Equal(want) {
+				t.Errorf("expected watch to be observed at %s, got %s", want, at)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("watch was not observed at %s", want)
+		}
+	}
+}
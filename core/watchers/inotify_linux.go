@@ -0,0 +1,335 @@
+package watchers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// ErrInotifyOverflow is reported when the inotify queue overflows and events
+// for the watched paths have been lost
+var ErrInotifyOverflow = errors.New("inotify queue overflowed")
+
+// inotifyMask is the set of inotify events watched for each path
+var inotifyMask = uint32(syscall.IN_CREATE | syscall.IN_MODIFY |
+	syscall.IN_DELETE | syscall.IN_DELETE_SELF | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_MOVE_SELF | syscall.IN_ATTRIB)
+
+// inotifyFallbackInterval is the poll interval (in seconds) used for paths
+// that cannot be watched with inotify
+var inotifyFallbackInterval = 1
+
+// InotifyWatcher is a Watcher that is told about changes to files and
+// directories by inotify as they happen, rather than polling them. Directories
+// are watched all the way down, including directories created in them. Paths
+// that cannot be watched with inotify are polled instead
+type InotifyWatcher struct {
+	paths   []string
+	stopper chan bool
+}
+
+// NewInotifyWatcher creates a new InotifyWatcher for the provided files and
+// directories
+func NewInotifyWatcher(paths []string) *InotifyWatcher {
+	iw := new(InotifyWatcher)
+	iw.paths = paths
+	iw.stopper = make(chan bool, 1)
+	return iw
+}
+
+// Watch the paths for changes
+func (iw InotifyWatcher) Watch(trigger core.WatchTrigger) core.WatcherCanceller {
+	return iw.WatchContext(context.Background(), trigger)
+}
+
+// WatchContext watches the paths for changes until the context is done or the
+// watcher is stopped
+func (iw InotifyWatcher) WatchContext(ctx context.Context,
+	trigger core.WatchTrigger) core.WatcherCanceller {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-iw.stopper:
+		}
+		cancel()
+	}()
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		log.Warn("Unable to use inotify, polling instead: %s", err)
+		for _, path := range iw.paths {
+			pollPath(ctx, path, trigger)
+		}
+		return iw.Stop
+	}
+
+	// The non-blocking fd is added to the runtime poller, so closing the file
+	// will end any read in progress
+	var inotify = os.NewFile(uintptr(fd), "inotify")
+	var reader = newInotifyReader(ctx, fd, trigger)
+
+	for _, path := range iw.paths {
+		reader.add(path)
+	}
+
+	go reader.read(inotify)
+	go func() {
+		<-ctx.Done()
+		inotify.Close()
+	}()
+
+	return iw.Stop
+}
+
+// Stop watching the paths
+func (iw InotifyWatcher) Stop() {
+	iw.stopper <- true
+}
+
+// inotifyReader turns inotify events into WatchEvents
+type inotifyReader struct {
+	ctx     context.Context
+	fd      int
+	trigger core.WatchTrigger
+	lock    sync.Mutex
+	paths   map[int32]string
+	roots   map[string]bool
+}
+
+func newInotifyReader(ctx context.Context, fd int,
+	trigger core.WatchTrigger) *inotifyReader {
+	reader := new(inotifyReader)
+	reader.ctx = ctx
+	reader.fd = fd
+	reader.trigger = trigger
+	reader.paths = make(map[int32]string)
+	reader.roots = make(map[string]bool)
+	return reader
+}
+
+// add a path to watch, along with the directories below it
+func (reader *inotifyReader) add(path string) {
+	reader.lock.Lock()
+	reader.roots[path] = true
+	reader.lock.Unlock()
+
+	reader.addTree(path)
+}
+
+// addTree adds inotify watches for a path and, if it is a directory, all the
+// directories below it, as inotify only reports changes in the top level of a
+// directory
+func (reader *inotifyReader) addTree(path string) {
+	reader.addWatch(path)
+
+	var info, err = os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	filepath.Walk(path, func(subPath string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() && subPath != path {
+			reader.addWatch(subPath)
+		}
+		return nil
+	})
+}
+
+// addWatch adds an inotify watch for the path, polling the path if that is not
+// possible
+func (reader *inotifyReader) addWatch(path string) {
+	wd, err := syscall.InotifyAddWatch(reader.fd, path, inotifyMask)
+	if err != nil {
+		if err == syscall.ENOSPC {
+			log.Warn("inotify watch limit reached, polling %s", path)
+		} else {
+			log.Warn("Unable to inotify watch %s, polling instead: %s", path, err)
+		}
+		pollPath(reader.ctx, path, reader.trigger)
+		return
+	}
+
+	reader.lock.Lock()
+	reader.paths[int32(wd)] = path
+	reader.lock.Unlock()
+}
+
+// read events from the inotify file until it is closed
+func (reader *inotifyReader) read(inotify *os.File) {
+	var buffer = make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		n, err := inotify.Read(buffer)
+		if err != nil {
+			if reader.ctx.Err() == nil {
+				log.Error("Failed to read inotify events: %s", err)
+				notifyError(reader.trigger, err)
+			}
+			return
+		}
+
+		var offset = 0
+		for offset+syscall.SizeofInotifyEvent <= n {
+			var raw = (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			var nameStart = offset + syscall.SizeofInotifyEvent
+			var nameEnd = nameStart + int(raw.Len)
+			if nameEnd > n {
+				break
+			}
+
+			var name = string(trimNulls(buffer[nameStart:nameEnd]))
+			reader.handle(raw.Wd, raw.Mask, name)
+
+			offset = nameEnd
+		}
+	}
+}
+
+// handle a single inotify event
+func (reader *inotifyReader) handle(wd int32, mask uint32, name string) {
+	// An overflow is not for any watch, its wd is -1
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		log.Warn("inotify queue overflowed, events have been lost")
+		notifyError(reader.trigger, ErrInotifyOverflow)
+		return
+	}
+
+	reader.lock.Lock()
+	path, ok := reader.paths[wd]
+	if ok && mask&syscall.IN_IGNORED != 0 {
+		delete(reader.paths, wd)
+	}
+	reader.lock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if mask&syscall.IN_IGNORED != 0 {
+		reader.lock.Lock()
+		var root = reader.roots[path]
+		reader.lock.Unlock()
+
+		// The watch has gone, so carry on watching the path another way.
+		// Directories below the paths are reported by their parent instead
+		if root && reader.ctx.Err() == nil {
+			if _, err := os.Lstat(path); err == nil {
+				reader.addTree(path)
+			} else {
+				pollPath(reader.ctx, path, reader.trigger)
+			}
+		}
+		return
+	}
+
+	var fileName = path
+	if len(name) > 0 {
+		fileName = filepath.Join(path, name)
+	}
+
+	if mask&syscall.IN_ISDIR != 0 &&
+		mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+		reader.addTree(fileName)
+	}
+
+	var modTime = time.Now()
+	if info, err := os.Lstat(fileName); err == nil {
+		modTime = info.ModTime()
+	}
+
+	reader.trigger.OnEvent(core.NewWatchEvent(map[string]interface{}{
+		core.FileName:      fileName,
+		core.FileModTime:   modTime,
+		core.FileOperation: inotifyOperation(mask),
+	}))
+}
+
+// inotifyOperation gets the file operation for an inotify event mask
+func inotifyOperation(mask uint32) string {
+	switch {
+	case mask&syscall.IN_CREATE != 0:
+		return core.FileCreated
+	case mask&(syscall.IN_DELETE|syscall.IN_DELETE_SELF) != 0:
+		return core.FileDeleted
+	case mask&(syscall.IN_MOVED_FROM|syscall.IN_MOVED_TO|
+		syscall.IN_MOVE_SELF) != 0:
+		return core.FileRenamed
+	case mask&syscall.IN_ATTRIB != 0:
+		return core.FileAttributes
+	}
+	return core.FileModified
+}
+
+// pollPath polls a path for changes until the context is done
+func pollPath(ctx context.Context, path string, trigger core.WatchTrigger) {
+	var watch = newPathPoll(path)
+	NewPollerWatcher(watch, inotifyFallbackInterval).WatchContext(ctx, trigger)
+}
+
+// pathPoll is a Watch that observes when a path is created, modified or
+// deleted, for paths that cannot be watched with inotify
+type pathPoll struct {
+	path    string
+	exists  bool
+	modTime time.Time
+}
+
+func newPathPoll(path string) *pathPoll {
+	poll := new(pathPoll)
+	poll.path = path
+	if info, err := os.Lstat(path); err == nil {
+		poll.exists = true
+		poll.modTime = info.ModTime()
+	}
+	return poll
+}
+
+// Observe whether the path has changed since it was last observed
+func (poll *pathPoll) Observe() *core.WatchEvent {
+	var info, err = os.Lstat(poll.path)
+	if err != nil {
+		if !poll.exists {
+			return nil
+		}
+		poll.exists = false
+		return poll.event(core.FileDeleted)
+	}
+
+	var operation = core.FileModified
+	if !poll.exists {
+		operation = core.FileCreated
+	} else if info.ModTime().Equal(poll.modTime) {
+		return nil
+	}
+
+	poll.exists = true
+	poll.modTime = info.ModTime()
+	return poll.event(operation)
+}
+
+func (poll *pathPoll) event(operation string) *core.WatchEvent {
+	return core.NewWatchEvent(map[string]interface{}{
+		core.FileName:      poll.path,
+		core.FileModTime:   poll.modTime,
+		core.FileOperation: operation,
+	})
+}
+
+// trimNulls removes the null padding from an inotify event name
+func trimNulls(name []byte) []byte {
+	for i, b := range name {
+		if b == 0 {
+			return name[:i]
+		}
+	}
+	return name
+}
@@ -0,0 +1,149 @@
+package watchers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// channelTrigger sends the events and errors it is told about to channels
+type channelTrigger struct {
+	events chan *core.WatchEvent
+	errors chan error
+}
+
+func newChannelTrigger() *channelTrigger {
+	trigger := new(channelTrigger)
+	trigger.events = make(chan *core.WatchEvent, 100)
+	trigger.errors = make(chan error, 10)
+	return trigger
+}
+
+func (trigger *channelTrigger) OnEvent(event *core.WatchEvent) {
+	trigger.events <- event
+}
+
+func (trigger *channelTrigger) OnError(err error) {
+	trigger.errors <- err
+}
+
+// expect waits for an event for the file with the operation, skipping any
+// other events
+func (trigger *channelTrigger) expect(t *testing.T, fileName string,
+	operation string, timeout time.Duration) {
+	t.Helper()
+
+	var deadline = time.After(timeout)
+	for {
+		select {
+		case event := <-trigger.events:
+			if event.Get(core.FileName) == fileName &&
+				event.Get(core.FileOperation) == operation {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("expected %s of %s", operation, fileName)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInotifyWatcher(t *testing.T) {
+	var dir = t.TempDir()
+	var sub = filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var trigger = newChannelTrigger()
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	NewInotifyWatcher([]string{dir}).WatchContext(ctx, trigger)
+
+	var file = filepath.Join(dir, "a.txt")
+	writeFile(t, file, "one")
+	trigger.expect(t, file, core.FileCreated, 5*time.Second)
+
+	writeFile(t, file, "two")
+	trigger.expect(t, file, core.FileModified, 5*time.Second)
+
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	trigger.expect(t, file, core.FileDeleted, 5*time.Second)
+
+	// Directories below the path are watched, including new ones
+	var subFile = filepath.Join(sub, "b.txt")
+	writeFile(t, subFile, "one")
+	trigger.expect(t, subFile, core.FileCreated, 5*time.Second)
+
+	var newDir = filepath.Join(dir, "new")
+	if err := os.Mkdir(newDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	trigger.expect(t, newDir, core.FileCreated, 5*time.Second)
+
+	var newFile = filepath.Join(newDir, "c.txt")
+	writeFile(t, newFile, "one")
+	trigger.expect(t, newFile, core.FileCreated, 5*time.Second)
+}
+
+func TestInotifyOverflow(t *testing.T) {
+	var trigger = newChannelTrigger()
+	var reader = newInotifyReader(context.Background(), -1, trigger)
+
+	reader.handle(-1, syscall.IN_Q_OVERFLOW, "")
+
+	select {
+	case err := <-trigger.errors:
+		if err != ErrInotifyOverflow {
+			t.Errorf("expected the overflow error, got %v", err)
+		}
+	default:
+		t.Error("expected the trigger to be told about the overflow")
+	}
+	if len(trigger.events) != 0 {
+		t.Error("expected no event for an overflow")
+	}
+}
+
+func TestInotifyPollingFallback(t *testing.T) {
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "a.txt")
+
+	var trigger = newChannelTrigger()
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	// Paths that cannot be watched with inotify, here because there is no
+	// inotify fd, are polled
+	var reader = newInotifyReader(ctx, -1, trigger)
+	reader.add(file)
+
+	writeFile(t, file, "one")
+	trigger.expect(t, file, core.FileCreated, 5*time.Second)
+
+	// Make sure the modified time changes
+	var later = time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	trigger.expect(t, file, core.FileModified, 5*time.Second)
+
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	trigger.expect(t, file, core.FileDeleted, 5*time.Second)
+}
@@ -1,6 +1,7 @@
 package watchers
 
 import (
+	"context"
 	"time"
 
 	"github.com/deanydean/clockwork/core"
@@ -18,20 +19,40 @@ type PollerWatcher struct {
 
 // Watch the Watch by polling it's Observe function
 func (pw PollerWatcher) Watch(trigger core.WatchTrigger) core.WatcherCanceller {
+	return pw.WatchContext(context.Background(), trigger)
+}
+
+// WatchContext polls the Watch until the context is done or the watcher is
+// stopped. Observations are cancelled when polling ends
+func (pw PollerWatcher) WatchContext(ctx context.Context,
+	trigger core.WatchTrigger) core.WatcherCanceller {
+	var poll = core.ContextWatchFor(pw.poll)
+	ctx, cancel := context.WithCancel(ctx)
+
 	go func() {
+		defer cancel()
+
 		var polling = true
 		for polling {
-			result := pw.poll.Observe()
-			if result != nil {
+			results, err := observeEvents(ctx, poll)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				notifyError(trigger, err)
+			}
+			for _, result := range results {
 				log.Debug("Got result=%s from watch=%s", result.Data, pw.poll)
 				trigger.OnEvent(result)
 			}
 
 			select {
+			case <-ctx.Done():
+				polling = false
 			case stopSignal := <-pw.stopper:
 				polling = !stopSignal
-			default:
-				time.Sleep(time.Duration(pw.interval) * time.Second)
+			case <-time.After(time.Duration(pw.interval) * time.Second):
 			}
 		}
 	}()
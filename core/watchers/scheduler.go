@@ -0,0 +1,185 @@
+package watchers
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// Schedule describes when a watch should be observed
+type Schedule struct {
+	// Interval between observations
+	Interval time.Duration
+	// Delay before the first observation
+	Delay time.Duration
+	// Jitter is the maximum random time added to each observation
+	Jitter time.Duration
+}
+
+// DefaultSchedule observes a watch every second
+var DefaultSchedule = Schedule{Interval: time.Second}
+
+// ScheduledRun is the next time a watch will be observed
+type ScheduledRun struct {
+	Watch core.Watch
+	Next  time.Time
+}
+
+// scheduledWatch is a watch waiting in a scheduler
+type scheduledWatch struct {
+	watch    core.Watch
+	observer core.ContextWatch
+	schedule Schedule
+	next     time.Time
+	index    int
+	running  bool
+}
+
+// scheduleHeap is a heap of scheduledWatches ordered by their next run time
+type scheduleHeap []*scheduledWatch
+
+func (h scheduleHeap) Len() int {
+	return len(h)
+}
+
+func (h scheduleHeap) Less(i, j int) bool {
+	return h[i].next.Before(h[j].next)
+}
+
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x interface{}) {
+	entry := x.(*scheduledWatch)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *scheduleHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.index = -1
+	*h = old[:n-1]
+	return entry
+}
+
+// scheduler holds watches ordered by when they should next be observed
+type scheduler struct {
+	lock  sync.Mutex
+	queue scheduleHeap
+	wake  chan bool
+}
+
+func newScheduler() *scheduler {
+	s := new(scheduler)
+	s.wake = make(chan bool, 1)
+	return s
+}
+
+// add a watch to the scheduler, to be first observed after the schedule delay
+func (s *scheduler) add(watch core.Watch, schedule Schedule, now time.Time) {
+	if schedule.Interval <= 0 {
+		schedule.Interval = DefaultSchedule.Interval
+	}
+
+	entry := new(scheduledWatch)
+	entry.watch = watch
+	entry.observer = core.ContextWatchFor(watch)
+	entry.schedule = schedule
+	entry.next = now.Add(schedule.Delay + jitter(schedule.Jitter))
+
+	s.lock.Lock()
+	heap.Push(&s.queue, entry)
+	s.lock.Unlock()
+
+	// Wake anything waiting on the old next run time
+	select {
+	case s.wake <- true:
+	default:
+	}
+}
+
+// next gets the earliest time a watch is due, false if nothing is scheduled
+func (s *scheduler) next() (time.Time, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.queue) == 0 {
+		return time.Time{}, false
+	}
+	return s.queue[0].next, true
+}
+
+// due gets all the watches that are due at the provided time and marks them
+// as running. Watches that are still running from their last run skip this
+// one and are pushed back by their interval
+func (s *scheduler) due(now time.Time) []*scheduledWatch {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var due []*scheduledWatch
+	for len(s.queue) > 0 && !s.queue[0].next.After(now) {
+		entry := s.queue[0]
+		if entry.running {
+			log.Debug("Watch is still running, skipping this run")
+		} else {
+			entry.running = true
+			due = append(due, entry)
+		}
+
+		entry.next = now.Add(entry.schedule.Interval +
+			jitter(entry.schedule.Jitter))
+		heap.Fix(&s.queue, 0)
+	}
+	return due
+}
+
+// finished marks a watch as no longer running and schedules its next run from
+// when it finished
+func (s *scheduler) finished(entry *scheduledWatch, now time.Time) {
+	s.lock.Lock()
+	entry.running = false
+	entry.next = now.Add(entry.schedule.Interval + jitter(entry.schedule.Jitter))
+	if entry.index >= 0 {
+		heap.Fix(&s.queue, entry.index)
+	}
+	s.lock.Unlock()
+
+	// Wake anything waiting on the old next run time
+	select {
+	case s.wake <- true:
+	default:
+	}
+}
+
+// runs gets the next run time of every scheduled watch, earliest first
+func (s *scheduler) runs() []ScheduledRun {
+	s.lock.Lock()
+	var runs = make([]ScheduledRun, 0, len(s.queue))
+	for _, entry := range s.queue {
+		runs = append(runs, ScheduledRun{Watch: entry.watch, Next: entry.next})
+	}
+	s.lock.Unlock()
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Next.Before(runs[j].Next)
+	})
+	return runs
+}
+
+// jitter gets a random duration up to max
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
@@ -0,0 +1,77 @@
+package watchers
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// slowWatch is a watch that takes a while to observe and counts how many
+// observations are running at once
+type slowWatch struct {
+	running    int32
+	maxRunning int32
+	runs       int32
+}
+
+func (watch *slowWatch) Observe() *core.WatchEvent {
+	var running = atomic.AddInt32(&watch.running, 1)
+	for {
+		var max = atomic.LoadInt32(&watch.maxRunning)
+		if running <= max || atomic.CompareAndSwapInt32(&watch.maxRunning, max, running) {
+			break
+		}
+	}
+	atomic.AddInt32(&watch.runs, 1)
+
+	time.Sleep(50 * time.Millisecond)
+	atomic.AddInt32(&watch.running, -1)
+	return nil
+}
+
+func TestSchedulerSkipsRunningWatches(t *testing.T) {
+	var s = newScheduler()
+	var start = time.Unix(1000, 0)
+	s.add(new(slowWatch), Schedule{Interval: time.Second}, start)
+
+	var due = s.due(start)
+	if len(due) != 1 {
+		t.Fatalf("expected 1 due watch, got %d", len(due))
+	}
+
+	if again := s.due(start.Add(time.Second)); len(again) != 0 {
+		t.Fatalf("expected running watch to be skipped, got %d due", len(again))
+	}
+
+	var finishedAt = start.Add(1500 * time.Millisecond)
+	s.finished(due[0], finishedAt)
+
+	if next, _ := s.next(); !next.Equal(finishedAt.Add(time.Second)) {
+		t.Errorf("expected next run %s after it finished, got %s",
+			finishedAt.Add(time.Second), next)
+	}
+	if again := s.due(finishedAt.Add(time.Second)); len(again) != 1 {
+		t.Errorf("expected finished watch to be due again, got %d due", len(again))
+	}
+}
+
+func TestWatchManDoesNotOverlapObservations(t *testing.T) {
+	var watch = new(slowWatch)
+	var watchMan = NewWatchMan(nil)
+	watchMan.Add(watch, Schedule{Interval: 10 * time.Millisecond})
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	watchMan.WatchContext(ctx, nil)
+	<-ctx.Done()
+
+	if max := atomic.LoadInt32(&watch.maxRunning); max != 1 {
+		t.Errorf("expected 1 observation at a time, got %d", max)
+	}
+	if runs := atomic.LoadInt32(&watch.runs); runs < 2 {
+		t.Errorf("expected the watch to run more than once, got %d", runs)
+	}
+}
@@ -0,0 +1,32 @@
+package watchers
+
+import (
+	"context"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// notifyError tells the trigger that a watch failed to observe, if the trigger
+// is able to handle errors
+func notifyError(trigger core.WatchTrigger, err error) {
+	if errorTrigger, ok := trigger.(core.WatchErrorTrigger); ok {
+		errorTrigger.OnError(err)
+		return
+	}
+
+	log.Debug("Failed to observe watch: %s", err)
+}
+
+// observeEvents gets all the events ready from a watch
+func observeEvents(ctx context.Context,
+	watch core.ContextWatch) ([]*core.WatchEvent, error) {
+	if multi, ok := watch.(core.MultiWatch); ok {
+		return multi.ObserveAll(ctx)
+	}
+
+	event, err := watch.ObserveContext(ctx)
+	if event == nil {
+		return nil, err
+	}
+	return []*core.WatchEvent{event}, err
+}
@@ -1,64 +1,84 @@
 package watchers
 
 import (
+	"context"
 	"time"
 
 	"github.com/deanydean/clockwork/core"
 )
 
-// WatchMan is a Watcher that links a number of Watches to a WatchTrigger
+// WatchMan is a Watcher that links a number of Watches to a WatchTrigger,
+// observing each Watch on its own Schedule
 type WatchMan struct {
-	watches  []core.Watch
-	trigger  *core.WatchTrigger
-	interval int
-	stopper  chan bool
+	scheduler *scheduler
+	stopper   chan bool
 }
 
-// NewWatchMan creates a new WatchMan
+// NewWatchMan creates a new WatchMan that observes each watch on the
+// DefaultSchedule
 func NewWatchMan(watches []core.Watch) *WatchMan {
 	wm := new(WatchMan)
-	wm.watches = watches
-
-	wm.interval = 1
+	wm.scheduler = newScheduler()
 	wm.stopper = make(chan bool, 1)
 
+	for w := range watches {
+		wm.Add(watches[w], DefaultSchedule)
+	}
+
 	return wm
 }
 
+// Add a watch to be observed on the provided schedule
+func (wm WatchMan) Add(watch core.Watch, schedule Schedule) {
+	wm.scheduler.add(watch, schedule, time.Now())
+}
+
+// NextRuns gets when each watch will next be observed, earliest first
+func (wm WatchMan) NextRuns() []ScheduledRun {
+	return wm.scheduler.runs()
+}
+
 // Watch tells the WatchMan to start watching
 func (wm WatchMan) Watch(trigger core.WatchTrigger) core.WatcherCanceller {
+	return wm.WatchContext(context.Background(), trigger)
+}
+
+// WatchContext tells the WatchMan to start watching until the context is done
+// or the WatchMan is stopped. Observations in progress are cancelled when
+// watching ends
+func (wm WatchMan) WatchContext(ctx context.Context,
+	trigger core.WatchTrigger) core.WatcherCanceller {
+	ctx, cancel := context.WithCancel(ctx)
+
 	go func() {
+		defer cancel()
+
 		// Poll until polling is unset
 		var polling = true
 		for polling {
-			for w := range wm.watches {
-				watch := wm.watches[w]
-
-				// Observe the watch value
-				go func() {
-					result := watch.Observe()
-					if result != nil {
-						log.Debug("Got result=%s", result.Data)
-
-						if result.ShouldStop() {
-							log.Warn("Watch needs to stop")
-							wm.stopper <- true
-							return
-						}
-
-						// Send the trigger
-						go func() {
-							trigger.OnEvent(result)
-						}()
-					}
-				}()
+			// Wait for the next watch to be due
+			var timer *time.Timer
+			var due <-chan time.Time
+			if next, ok := wm.scheduler.next(); ok {
+				timer = time.NewTimer(time.Until(next))
+				due = timer.C
 			}
 
 			select {
+			case <-ctx.Done():
+				polling = false
 			case stopSignal := <-wm.stopper:
 				polling = !stopSignal
-			default:
-				time.Sleep(time.Duration(wm.interval) * time.Second)
+			case <-wm.scheduler.wake:
+				// The schedule has changed
+			case now := <-due:
+				for _, entry := range wm.scheduler.due(now) {
+					go wm.observe(ctx, cancel, entry, trigger)
+				}
+			}
+
+			if timer != nil {
+				timer.Stop()
 			}
 		}
 	}()
@@ -67,6 +87,40 @@ func (wm WatchMan) Watch(trigger core.WatchTrigger) core.WatcherCanceller {
 	return wm.Stop
 }
 
+// observe a scheduled watch and send the result to the trigger. The watch is
+// scheduled again once it has been observed
+func (wm WatchMan) observe(ctx context.Context, cancel context.CancelFunc,
+	entry *scheduledWatch, trigger core.WatchTrigger) {
+	results, err := observeEvents(ctx, entry.observer)
+	wm.scheduler.finished(entry, time.Now())
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		notifyError(trigger, err)
+	}
+
+	for _, result := range results {
+		log.Debug("Got result=%s", result.Data)
+
+		if result.ShouldStop() {
+			log.Warn("Watch needs to stop")
+			cancel()
+			return
+		}
+	}
+
+	// Send the trigger, keeping the events in order
+	if len(results) > 0 {
+		go func() {
+			for _, result := range results {
+				trigger.OnEvent(result)
+			}
+		}()
+	}
+}
+
 // Stop watching for events
 func (wm WatchMan) Stop() {
 	wm.stopper <- true
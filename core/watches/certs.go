@@ -0,0 +1,235 @@
+package watches
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// CertSource is a key in WatchEvent for where a certificate was read from
+var CertSource = "cert.source"
+
+// CertSubject is a key in WatchEvent for the subject of a certificate
+var CertSubject = "cert.subject"
+
+// CertNotAfter is a key in WatchEvent for when a certificate expires
+var CertNotAfter = "cert.notafter"
+
+// CertDaysLeft is a key in WatchEvent for the days until a certificate expires
+var CertDaysLeft = "cert.daysleft"
+
+// CertFingerprint is a key in WatchEvent for the SHA-256 fingerprint of the
+// leaf certificate
+var CertFingerprint = "cert.fingerprint"
+
+// CertPreviousFingerprint is a key in WatchEvent for the SHA-256 fingerprint
+// of the leaf certificate when it was last observed
+var CertPreviousFingerprint = "cert.previousfingerprint"
+
+// CertStatus is a key in WatchEvent for the statuses of a certificate
+var CertStatus = "cert.status"
+
+// CertExpiring is in CertStatus when a certificate will expire soon
+var CertExpiring = "expiring"
+
+// CertExpired is in CertStatus when a certificate has expired
+var CertExpired = "expired"
+
+// CertChanged is in CertStatus when the leaf certificate has changed
+var CertChanged = "changed"
+
+// defaultCertTimeout is how long to wait when connecting to get certificates
+var defaultCertTimeout = 10 * time.Second
+
+// CertExpiryWatch is a Watch that observes a certificate chain, from a TLS
+// server or a PEM file, expiring or changing
+type CertExpiryWatch struct {
+	address     string
+	serverName  string
+	file        string
+	days        int
+	fingerprint string
+}
+
+// NewCertExpiryWatch creates a new CertExpiryWatch that warns when a
+// certificate is within days of expiring. The target can be a host:port, an
+// https:// URL or the path to a PEM file
+func NewCertExpiryWatch(target string, days int) *CertExpiryWatch {
+	watch := new(CertExpiryWatch)
+	watch.days = days
+
+	if strings.Contains(target, "://") {
+		var certURL, err = url.Parse(target)
+		if err != nil {
+			log.Error("Invalid certificate url %s : %s", target, err)
+			return nil
+		}
+
+		if certURL.Scheme == "file" {
+			watch.file = certURL.Path
+		} else {
+			watch.setAddress(certURL.Host)
+		}
+	} else if utils.PathExists(target) {
+		watch.file = target
+	} else {
+		watch.setAddress(target)
+	}
+
+	return watch
+}
+
+// setAddress sets the address of the TLS server, using port 443 if there isn't
+// one. IPv6 addresses can be bare or in brackets, with or without a port
+func (watch *CertExpiryWatch) setAddress(address string) {
+	var host, port, err = net.SplitHostPort(address)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+		port = "443"
+	}
+	watch.address = net.JoinHostPort(host, port)
+
+	// SNI is only sent for host names, not IP addresses
+	var ip = host
+	if zone := strings.LastIndex(ip, "%"); zone >= 0 {
+		ip = ip[:zone]
+	}
+	if net.ParseIP(ip) == nil {
+		watch.serverName = host
+	} else {
+		watch.serverName = ""
+	}
+}
+
+// source gets where the certificates are read from
+func (watch *CertExpiryWatch) source() string {
+	if len(watch.file) > 0 {
+		return watch.file
+	}
+	return watch.address
+}
+
+// Observe whether a certificate is expiring or has changed
+func (watch *CertExpiryWatch) Observe() *core.WatchEvent {
+	return observe(watch.source(), watch)
+}
+
+// ObserveContext observes whether a certificate in the chain is expiring or
+// has expired, or the leaf certificate has changed
+func (watch *CertExpiryWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	var chain []*x509.Certificate
+	var err error
+
+	if len(watch.file) > 0 {
+		chain, err = readCertificates(watch.file)
+	} else {
+		chain, err = watch.dialCertificates(ctx)
+	}
+	if err != nil {
+		return nil, core.NewWatchError(watch.source(), err)
+	}
+	if len(chain) == 0 {
+		return nil, core.NewWatchError(watch.source(),
+			fmt.Errorf("no certificates found"))
+	}
+
+	var status []string
+	var now = time.Now()
+
+	// Report on the certificate that expires first
+	var expiring = chain[0]
+	for _, cert := range chain[1:] {
+		if cert.NotAfter.Before(expiring.NotAfter) {
+			expiring = cert
+		}
+	}
+
+	var daysLeft = int(expiring.NotAfter.Sub(now).Hours() / 24)
+	if now.After(expiring.NotAfter) {
+		status = append(status, CertExpired)
+	} else if daysLeft < watch.days {
+		status = append(status, CertExpiring)
+	}
+
+	var sum = sha256.Sum256(chain[0].Raw)
+	var fingerprint = hex.EncodeToString(sum[:])
+	var previous = watch.fingerprint
+	if len(previous) > 0 && fingerprint != previous {
+		status = append(status, CertChanged)
+	}
+	watch.fingerprint = fingerprint
+
+	if len(status) == 0 {
+		return nil, nil
+	}
+
+	return core.NewWatchEvent(map[string]interface{}{
+		CertSource:              watch.source(),
+		CertSubject:             expiring.Subject.String(),
+		CertNotAfter:            expiring.NotAfter,
+		CertDaysLeft:            daysLeft,
+		CertFingerprint:         fingerprint,
+		CertPreviousFingerprint: previous,
+		CertStatus:              status,
+	}), nil
+}
+
+// dialCertificates gets the certificate chain presented by the TLS server.
+// The chain is not verified, so expired certificates can still be inspected
+func (watch *CertExpiryWatch) dialCertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	var dialer = &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: defaultCertTimeout},
+		Config: &tls.Config{
+			ServerName:         watch.serverName,
+			InsecureSkipVerify: true,
+		},
+	}
+
+	var conn, err = dialer.DialContext(ctx, "tcp", watch.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.(*tls.Conn).ConnectionState().PeerCertificates, nil
+}
+
+// readCertificates reads all the certificates in a PEM file
+func readCertificates(file string) ([]*x509.Certificate, error) {
+	var data, err = ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		var cert, parseErr = x509.ParseCertificate(block.Bytes)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,102 @@
+package watches
+
+import (
+	"context"
+	"io/ioutil"
+	golog "log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCertExpiryWatchAddress(t *testing.T) {
+	var tests = []struct {
+		target     string
+		address    string
+		serverName string
+	}{
+		{"example.com", "example.com:443", "example.com"},
+		{"example.com:8443", "example.com:8443", "example.com"},
+		{"https://example.com", "example.com:443", "example.com"},
+		{"https://example.com:8443/health", "example.com:8443", "example.com"},
+		{"192.0.2.1", "192.0.2.1:443", ""},
+		{"192.0.2.1:8443", "192.0.2.1:8443", ""},
+		{"https://192.0.2.1", "192.0.2.1:443", ""},
+		{"2001:db8::1", "[2001:db8::1]:443", ""},
+		{"[2001:db8::1]", "[2001:db8::1]:443", ""},
+		{"[2001:db8::1]:8443", "[2001:db8::1]:8443", ""},
+		{"https://[2001:db8::1]", "[2001:db8::1]:443", ""},
+		{"https://[2001:db8::1]:8443/", "[2001:db8::1]:8443", ""},
+		{"[fe80::1%eth0]", "[fe80::1%eth0]:443", ""},
+	}
+
+	for _, test := range tests {
+		var watch = NewCertExpiryWatch(test.target, 30)
+		if watch == nil {
+			t.Errorf("%s: failed to create watch", test.target)
+			continue
+		}
+		if watch.address != test.address {
+			t.Errorf("%s: expected address %s, got %s", test.target,
+				test.address, watch.address)
+		}
+		if watch.serverName != test.serverName {
+			t.Errorf("%s: expected server name %q, got %q", test.target,
+				test.serverName, watch.serverName)
+		}
+	}
+}
+
+// newTLSServer starts a TLS server listening on the network, or skips the
+// test if that network is not available
+func newTLSServer(t *testing.T, network string, address string) *httptest.Server {
+	var listener, err = net.Listen(network, address)
+	if err != nil {
+		t.Skipf("cannot listen on %s: %s", address, err)
+	}
+
+	// The watch hangs up once it has the certificates, which the server logs
+	var server = httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.Config.ErrorLog = golog.New(ioutil.Discard, "", 0)
+	server.Listener.Close()
+	server.Listener = listener
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCertExpiryWatchDials(t *testing.T) {
+	var tests = []struct {
+		network string
+		address string
+	}{
+		{"tcp4", "127.0.0.1:0"},
+		{"tcp6", "[::1]:0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.network, func(t *testing.T) {
+			var server = newTLSServer(t, test.network, test.address)
+
+			// The test certificate expires long after any sensible threshold
+			var watch = NewCertExpiryWatch(server.URL, 1000000)
+			var event, err = watch.ObserveContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event == nil {
+				t.Fatal("expected the certificate to be expiring")
+			}
+
+			var status = event.Get(CertStatus).([]string)
+			if len(status) != 1 || status[0] != CertExpiring {
+				t.Errorf("expected %s status, got %v", CertExpiring, status)
+			}
+			if "https://"+event.GetAsString(CertSource) != server.URL {
+				t.Errorf("expected source %s, got %s", server.URL,
+					event.GetAsString(CertSource))
+			}
+		})
+	}
+}
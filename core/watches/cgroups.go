@@ -0,0 +1,311 @@
+package watches
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/cgroups"
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// ErrCgroupNotFound is returned when a watched cgroup does not exist
+var ErrCgroupNotFound = errors.New("cgroup not found")
+
+// CgroupPath is a key in WatchEvent for the path of a cgroup
+var CgroupPath = "cgroup.path"
+
+// CgroupCPU is a key in WatchEvent for the CPU usage of a cgroup over the
+// interval since it was last observed, as a percentage of one core
+var CgroupCPU = "cgroup.cpu"
+
+// CgroupCPUThrottled is a key in WatchEvent for the number of periods a cgroup
+// was throttled in since it was last observed
+var CgroupCPUThrottled = "cgroup.cpu_throttled"
+
+// CgroupMemory is a key in WatchEvent for the memory used by a cgroup in bytes
+var CgroupMemory = "cgroup.memory"
+
+// CgroupMemoryLimit is a key in WatchEvent for the memory limit of a cgroup in
+// bytes, if it has one
+var CgroupMemoryLimit = "cgroup.memory_limit"
+
+// CgroupOOMKills is a key in WatchEvent for the number of processes in a
+// cgroup killed by the OOM killer
+var CgroupOOMKills = "cgroup.oom_kills"
+
+// CgroupNewOOMKills is a key in WatchEvent for the number of processes in a
+// cgroup killed by the OOM killer since it was last observed
+var CgroupNewOOMKills = "cgroup.new_oom_kills"
+
+// CgroupPids is a key in WatchEvent for the number of processes in a cgroup
+var CgroupPids = "cgroup.pids"
+
+// CgroupReadsPerSec is a key in WatchEvent for the bytes read per second by a
+// cgroup from all devices
+var CgroupReadsPerSec = "cgroup.reads_per_sec"
+
+// CgroupWritesPerSec is a key in WatchEvent for the bytes written per second
+// by a cgroup to all devices
+var CgroupWritesPerSec = "cgroup.writes_per_sec"
+
+// CgroupCPUPressure is a key in WatchEvent for the percentage of the last 10
+// seconds that some tasks in a cgroup were stalled waiting for CPU
+var CgroupCPUPressure = "cgroup.cpu_pressure"
+
+// CgroupMemoryPressure is a key in WatchEvent for the percentage of the last
+// 10 seconds that some tasks in a cgroup were stalled waiting for memory
+var CgroupMemoryPressure = "cgroup.memory_pressure"
+
+// CgroupExceeded is a key in WatchEvent for the thresholds that were
+// exceeded, including CgroupNewOOMKills if there were any
+var CgroupExceeded = "cgroup.exceeded"
+
+// CgroupThresholds are the values above which a CgroupWatch reports a cgroup.
+// Thresholds that are 0 are not checked
+type CgroupThresholds struct {
+	CPU            float64
+	Memory         int64
+	MemoryRatio    float64
+	Pids           int64
+	ReadsPerSec    float64
+	WritesPerSec   float64
+	CPUPressure    float64
+	MemoryPressure float64
+}
+
+// cgroupSample is the counters of a cgroup when it was last observed, and
+// whether the controllers they are from were enabled
+type cgroupSample struct {
+	time        time.Time
+	hasCPU      bool
+	usageUsec   uint64
+	nrThrottled uint64
+	hasIO       bool
+	readBytes   uint64
+	writeBytes  uint64
+	hasEvents   bool
+	oomKills    uint64
+}
+
+// CgroupWatch is a Watch that observes the resources used by a cgroup v2
+// group. Only the files of the controllers enabled for the group are read
+type CgroupWatch struct {
+	group      cgroups.Group
+	proc       *procfs.Proc
+	cgroupFS   cgroups.FS
+	thresholds CgroupThresholds
+	last       *cgroupSample
+}
+
+// NewCgroupWatch creates a new CgroupWatch for the provided group, reporting
+// when its usage exceeds the thresholds or processes in it are OOM killed
+func NewCgroupWatch(group cgroups.Group,
+	thresholds CgroupThresholds) *CgroupWatch {
+	watch := new(CgroupWatch)
+	watch.group = group
+	watch.thresholds = thresholds
+
+	// Init the watch with an initial value
+	watch.Observe()
+
+	return watch
+}
+
+// NewProcessCgroupWatch creates a new CgroupWatch for the group that the
+// provided pid in the proc filesystem is in, in the cgroup filesystem. The
+// group is found again each time it is observed, in case the process moves
+func NewProcessCgroupWatch(procFS procfs.FS, cgroupFS cgroups.FS, pid int,
+	thresholds CgroupThresholds) *CgroupWatch {
+	watch := new(CgroupWatch)
+	watch.cgroupFS = cgroupFS
+	watch.thresholds = thresholds
+
+	var proc = procFS.Proc(pid)
+	watch.proc = &proc
+
+	// Init the watch with an initial value
+	watch.Observe()
+
+	return watch
+}
+
+// Observe whether a cgroup is using too many resources
+func (watch *CgroupWatch) Observe() *core.WatchEvent {
+	return observe("cgroup", watch)
+}
+
+// ObserveContext observes the usage of a cgroup, returning an event if the
+// usage since the last observation exceeds the thresholds or processes in it
+// have been OOM killed
+func (watch *CgroupWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if watch.proc != nil {
+		var group, err = watch.cgroupFS.GroupOf(*watch.proc)
+		if err != nil {
+			if !watch.proc.Exists() {
+				err = ErrProcessNotFound
+			}
+			return nil, core.NewWatchError("cgroup", err)
+		}
+		if group.Path != watch.group.Path {
+			// Rates can't be worked out across different groups
+			watch.last = nil
+		}
+		watch.group = group
+	}
+
+	if !watch.group.Exists() {
+		return nil, core.NewWatchError(watch.group.Path, ErrCgroupNotFound)
+	}
+
+	var data = map[string]interface{}{
+		CgroupPath: watch.group.Path,
+	}
+	var sample = cgroupSample{time: time.Now()}
+	var readErr error
+
+	// read records the first error that isn't from a controller that is not
+	// enabled for the group
+	var read = func(err error) bool {
+		if err != nil && !os.IsNotExist(err) && readErr == nil {
+			readErr = err
+		}
+		return err == nil
+	}
+
+	var cpuStat, cpuErr = watch.group.CPUStat()
+	sample.hasCPU = read(cpuErr)
+	if sample.hasCPU {
+		sample.usageUsec = cpuStat.UsageUsec
+		sample.nrThrottled = cpuStat.NrThrottled
+	}
+
+	var memory, memoryErr = watch.group.MemoryCurrent()
+	if read(memoryErr) {
+		data[CgroupMemory] = memory
+	}
+
+	var limit, limitErr = watch.group.MemoryLimit()
+	if read(limitErr) && limit != cgroups.Max {
+		data[CgroupMemoryLimit] = limit
+	}
+
+	var memoryEvents, eventsErr = watch.group.MemoryEvents()
+	sample.hasEvents = read(eventsErr)
+	if sample.hasEvents {
+		sample.oomKills = memoryEvents.OOMKill
+		data[CgroupOOMKills] = memoryEvents.OOMKill
+	}
+
+	var pids, pidsErr = watch.group.PidsCurrent()
+	if read(pidsErr) {
+		data[CgroupPids] = pids
+	}
+
+	var ioStats, ioErr = watch.group.IOStat()
+	sample.hasIO = read(ioErr)
+	for _, stat := range ioStats {
+		sample.readBytes += stat.ReadBytes
+		sample.writeBytes += stat.WriteBytes
+	}
+
+	var pressures = map[string]string{
+		procfs.PressureCPU:    CgroupCPUPressure,
+		procfs.PressureMemory: CgroupMemoryPressure,
+	}
+	for resource, key := range pressures {
+		var pressure, pressureErr = watch.group.Pressure(resource)
+		if read(pressureErr) {
+			data[key] = pressure.Some.Avg10
+		}
+	}
+
+	if readErr != nil {
+		return nil, core.NewWatchError(watch.group.Path, readErr)
+	}
+
+	var last = watch.last
+	watch.last = &sample
+
+	if last == nil {
+		// Nothing to compare with yet
+		return nil, nil
+	}
+
+	var since = sample.time.Sub(last.time).Seconds()
+	if since <= 0 {
+		return nil, nil
+	}
+
+	var rate = func(value uint64, lastValue uint64) float64 {
+		if value < lastValue {
+			// The group has been replaced by one with the same path
+			return float64(value) / since
+		}
+		return float64(value-lastValue) / since
+	}
+
+	// Controllers that were enabled or disabled since the last observation
+	// have no rates until the next one
+	if sample.hasCPU && last.hasCPU {
+		data[CgroupCPU] = rate(sample.usageUsec, last.usageUsec) / 1e6 * 100
+		if sample.nrThrottled >= last.nrThrottled {
+			data[CgroupCPUThrottled] = sample.nrThrottled - last.nrThrottled
+		}
+	}
+	if sample.hasIO && last.hasIO {
+		data[CgroupReadsPerSec] = rate(sample.readBytes, last.readBytes)
+		data[CgroupWritesPerSec] = rate(sample.writeBytes, last.writeBytes)
+	}
+
+	var value = func(key string) float64 {
+		switch v := data[key].(type) {
+		case float64:
+			return v
+		case int64:
+			return float64(v)
+		}
+		return 0
+	}
+
+	var exceeded = exceededThresholds(map[string][2]float64{
+		CgroupCPU:            {value(CgroupCPU), watch.thresholds.CPU},
+		CgroupMemory:         {value(CgroupMemory), float64(watch.thresholds.Memory)},
+		CgroupPids:           {value(CgroupPids), float64(watch.thresholds.Pids)},
+		CgroupReadsPerSec:    {value(CgroupReadsPerSec), watch.thresholds.ReadsPerSec},
+		CgroupWritesPerSec:   {value(CgroupWritesPerSec), watch.thresholds.WritesPerSec},
+		CgroupCPUPressure:    {value(CgroupCPUPressure), watch.thresholds.CPUPressure},
+		CgroupMemoryPressure: {value(CgroupMemoryPressure), watch.thresholds.MemoryPressure},
+	})
+
+	if limit, ok := data[CgroupMemoryLimit].(int64); ok && limit > 0 &&
+		watch.thresholds.MemoryRatio > 0 &&
+		value(CgroupMemory)/float64(limit) > watch.thresholds.MemoryRatio {
+		exceeded = append(exceeded, CgroupMemoryLimit)
+	}
+
+	if sample.hasEvents && last.hasEvents && sample.oomKills > last.oomKills {
+		data[CgroupNewOOMKills] = sample.oomKills - last.oomKills
+		exceeded = append(exceeded, CgroupNewOOMKills)
+	}
+
+	log.Debug("cgroup=%s cpu=%f memory=%f pids=%f oom_kills=%d",
+		watch.group.Path, value(CgroupCPU), value(CgroupMemory),
+		value(CgroupPids), sample.oomKills)
+
+	if len(exceeded) == 0 {
+		// Nothing to report
+		return nil, nil
+	}
+
+	sort.Strings(exceeded)
+	data[CgroupExceeded] = exceeded
+	return core.NewWatchEvent(data), nil
+}
@@ -0,0 +1,129 @@
+package watches
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/cgroups"
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+func TestCgroupWatch(t *testing.T) {
+	var root = writeFixture(t, map[string]string{
+		"app/memory.current": "600\n",
+		"app/memory.max":     "1000\n",
+		"app/memory.events":  "oom_kill 1\n",
+		"app/pids.current":   "3\n",
+	})
+	var fs = cgroups.NewFS(root)
+
+	var write = func(name string, content string) {
+		if err := ioutil.WriteFile(filepath.Join(root, "app", name),
+			[]byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var watch = NewCgroupWatch(fs.Group("/app"), CgroupThresholds{
+		CPU:         1,
+		MemoryRatio: 0.9,
+		Pids:        5,
+	})
+
+	var observe = func(expected ...string) *core.WatchEvent {
+		t.Helper()
+		time.Sleep(10 * time.Millisecond)
+
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expected) == 0 {
+			if event != nil {
+				t.Fatalf("expected no event, got %v", event.Data)
+			}
+			return nil
+		}
+		if event == nil {
+			t.Fatalf("expected %v, got no event", expected)
+		}
+		if exceeded := event.Get(CgroupExceeded); !reflect.DeepEqual(exceeded, expected) {
+			t.Fatalf("expected %v, got %v", expected, exceeded)
+		}
+		return event
+	}
+
+	observe()
+
+	// The cpu controller being enabled has no rate until it is observed again
+	write("cpu.stat", "usage_usec 100000000\n")
+	write("io.stat", "8:0 rbytes=1000000 wbytes=0\n")
+	observe()
+
+	write("cpu.stat", "usage_usec 200000000\n")
+	write("pids.current", "6\n")
+	write("memory.current", "950\n")
+	var event = observe(CgroupCPU, CgroupMemoryLimit, CgroupPids)
+	if event.Get(CgroupMemoryLimit) != int64(1000) || event.Get(CgroupPids) != int64(6) {
+		t.Errorf("expected limit 1000 and 6 pids, got %v", event.Data)
+	}
+	if _, ok := event.Get(CgroupReadsPerSec).(float64); !ok {
+		t.Errorf("expected the read rate, got %v", event.Data)
+	}
+
+	write("memory.events", "oom_kill 3\n")
+	event = observe(CgroupMemoryLimit, CgroupNewOOMKills, CgroupPids)
+	if event.Get(CgroupNewOOMKills) != uint64(2) {
+		t.Errorf("expected 2 new OOM kills, got %v", event.Get(CgroupNewOOMKills))
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "app")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error when the group has gone")
+	}
+}
+
+func TestProcessCgroupWatchFollowsProcess(t *testing.T) {
+	var procFS = procfs.NewFS(writeFixture(t, map[string]string{
+		"10/cgroup": "0::/a\n",
+	}))
+	var cgroupFS = cgroups.NewFS(writeFixture(t, map[string]string{
+		"a/cpu.stat": "usage_usec 1000000000\n",
+		"b/cpu.stat": "usage_usec 0\n",
+	}))
+
+	var watch = NewProcessCgroupWatch(procFS, cgroupFS, 10,
+		CgroupThresholds{CPU: 1})
+	if watch.group.Path != "/a" {
+		t.Fatalf("expected the group of the process, got %q", watch.group.Path)
+	}
+
+	// Moving to another group starts the rates again
+	if err := ioutil.WriteFile(procFS.Path("10", "cgroup"), []byte("0::/b\n"),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	var event, err = watch.ObserveContext(context.Background())
+	if event != nil || err != nil {
+		t.Errorf("expected no event after moving group, got %v %v", event, err)
+	}
+	if watch.group.Path != "/b" {
+		t.Errorf("expected the new group of the process, got %q", watch.group.Path)
+	}
+
+	if err = os.RemoveAll(procFS.Path("10")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = watch.ObserveContext(context.Background()); !errors.Is(err,
+		ErrProcessNotFound) {
+		t.Errorf("expected the process to not be found, got %v", err)
+	}
+}
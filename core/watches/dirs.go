@@ -0,0 +1,134 @@
+package watches
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// FilesAdded is a key in WatchEvent for the paths of files added to a directory
+var FilesAdded = "file.added"
+
+// FilesRemoved is a key in WatchEvent for the paths of files removed from a
+// directory
+var FilesRemoved = "file.removed"
+
+// FilesModified is a key in WatchEvent for the paths of files modified in a
+// directory
+var FilesModified = "file.modified"
+
+// fileState is the state of a file when a directory was last observed
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// DirectoryWatch is a Watch that observes files being added, removed and
+// modified in a directory tree
+type DirectoryWatch struct {
+	dir       string
+	recursive bool
+	filter    utils.FilterFunc
+	exclude   utils.FilterFunc
+	files     map[string]fileState
+}
+
+// NewDirectoryWatch creates a new DirectoryWatch for the provided directory.
+// Only files with names matching the include globs (or all files if there are
+// none) and not matching the exclude globs are watched. Directories matching
+// the exclude globs are not descended into when recursive
+func NewDirectoryWatch(dir string, recursive bool, include []string,
+	exclude []string) *DirectoryWatch {
+	watch := new(DirectoryWatch)
+	watch.dir = dir
+	watch.recursive = recursive
+	watch.filter = utils.GlobFilter(include, exclude)
+	watch.exclude = utils.GlobFilter(nil, exclude)
+
+	if utils.PathExists(dir) {
+		watch.files = watch.scan()
+	} else {
+		log.Warn("Directory %s does not exist yet", dir)
+		watch.files = make(map[string]fileState)
+	}
+
+	return watch
+}
+
+// Observe whether files have been added, removed or modified in the directory
+func (watch *DirectoryWatch) Observe() *core.WatchEvent {
+	return observe(watch.dir, watch)
+}
+
+// ObserveContext observes whether files have been added, removed or modified
+// in the directory, returning an error if the directory does not exist
+func (watch *DirectoryWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(watch.dir); err != nil {
+		return nil, core.NewWatchError(watch.dir, err)
+	}
+
+	var files = watch.scan()
+	var added, removed, modified []string
+
+	for path, state := range files {
+		var last, ok = watch.files[path]
+		if !ok {
+			added = append(added, path)
+		} else if last != state {
+			modified = append(modified, path)
+		}
+	}
+	for path := range watch.files {
+		if _, ok := files[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+
+	watch.files = files
+
+	if len(added) == 0 && len(removed) == 0 && len(modified) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+
+	return core.NewWatchEvent(map[string]interface{}{
+		FileName:      watch.dir,
+		FilesAdded:    added,
+		FilesRemoved:  removed,
+		FilesModified: modified,
+	}), nil
+}
+
+// scan the directory for the files to watch
+func (watch *DirectoryWatch) scan() map[string]fileState {
+	var files = make(map[string]fileState)
+	watch.scanDir(watch.dir, files)
+	return files
+}
+
+func (watch *DirectoryWatch) scanDir(dir string, files map[string]fileState) {
+	for e := utils.FilesInDir(dir, utils.MatchAll).Front(); e != nil; e = e.Next() {
+		var info = e.Value.(os.FileInfo)
+		var path = filepath.Join(dir, info.Name())
+
+		if info.IsDir() {
+			if watch.recursive && watch.exclude(info.Name()) {
+				watch.scanDir(path, files)
+			}
+		} else if watch.filter(info.Name()) {
+			files[path] = fileState{info.ModTime(), info.Size()}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package watches
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDirectoryWatch(t *testing.T) {
+	var dir = writeFixture(t, map[string]string{
+		"a.log":           "a",
+		"b.txt":           "b",
+		"sub/c.log":       "c",
+		"sub/deep/d.log":  "d",
+		"skip/e.log":      "e",
+		"sub/ignored.tmp": "x",
+	})
+	var path = func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	var watch = NewDirectoryWatch(dir, true, []string{"*.log"},
+		[]string{"skip", "*.tmp"})
+	var expected = []string{path("a.log"), path("sub/c.log"), path("sub/deep/d.log")}
+	var files []string
+	for file := range watch.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected files %v, got %v", expected, files)
+	}
+
+	var observe = func(added []string, removed []string, modified []string) {
+		t.Helper()
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added == nil && removed == nil && modified == nil {
+			if event != nil {
+				t.Fatalf("expected no event, got %v", event.Data)
+			}
+			return
+		}
+		if event == nil {
+			t.Fatal("expected an event")
+		}
+		for key, expected := range map[string][]string{
+			FilesAdded:    added,
+			FilesRemoved:  removed,
+			FilesModified: modified,
+		} {
+			var value = event.Get(key).([]string)
+			if len(value) != len(expected) ||
+				(len(value) > 0 && !reflect.DeepEqual(value, expected)) {
+				t.Errorf("expected %s %v, got %v", key, expected, value)
+			}
+		}
+		if event.Get(FileName) != dir {
+			t.Errorf("expected the directory name, got %v", event.Get(FileName))
+		}
+	}
+
+	observe(nil, nil, nil)
+
+	// Files that do not match the globs, or are in excluded directories, are
+	// not watched
+	writeFile(t, path("new.txt"), "n")
+	writeFile(t, path("skip/f.log"), "f")
+	writeFile(t, path("sub/more.tmp"), "m")
+	observe(nil, nil, nil)
+
+	writeFile(t, path("sub/deep/new.log"), "n")
+	if err := os.Remove(path("a.log")); err != nil {
+		t.Fatal(err)
+	}
+	var later = time.Now().Add(time.Minute)
+	if err := os.Chtimes(path("sub/c.log"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	observe([]string{path("sub/deep/new.log")}, []string{path("a.log")},
+		[]string{path("sub/c.log")})
+	observe(nil, nil, nil)
+
+	// Removing a directory removes the files in it
+	if err := os.RemoveAll(path("sub/deep")); err != nil {
+		t.Fatal(err)
+	}
+	observe(nil, []string{path("sub/deep/d.log"), path("sub/deep/new.log")}, nil)
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error when the directory has gone")
+	}
+}
+
+func TestDirectoryWatchNotRecursive(t *testing.T) {
+	var dir = writeFixture(t, map[string]string{
+		"a.log":     "a",
+		"sub/b.log": "b",
+	})
+
+	var watch = NewDirectoryWatch(dir, false, nil, nil)
+	if len(watch.files) != 1 {
+		t.Fatalf("expected only the top level file, got %v", watch.files)
+	}
+
+	writeFile(t, filepath.Join(dir, "sub", "c.log"), "c")
+	if event, _ := watch.ObserveContext(context.Background()); event != nil {
+		t.Errorf("expected no event for a file in a subdirectory, got %v", event.Data)
+	}
+}
@@ -1,7 +1,7 @@
 package watches
 
 import (
-	"fmt"
+	"context"
 	"os"
 	"time"
 
@@ -9,18 +9,50 @@ import (
 )
 
 // FileName is a key in EventWatch for a file name
-var FileName = "file.name"
+var FileName = core.FileName
 
 // FileModTime is a key in EventWatch for a file's modified time
-var FileModTime = "file.modifiedtime"
+var FileModTime = core.FileModTime
 
-// FileModifiedWatch is a Watch that observes when a file modified time changes
+// FileDigest is a key in EventWatch for the SHA-256 digest of a file's content
+var FileDigest = "file.digest"
+
+// FilePreviousDigest is a key in EventWatch for the SHA-256 digest of a file's
+// previous content
+var FilePreviousDigest = "file.previousdigest"
+
+// FileDiff is a key in EventWatch for a unified diff of a file's text content
+var FileDiff = "file.diff"
+
+// FileOperation is a key in EventWatch for the operation performed on a file
+var FileOperation = core.FileOperation
+
+// FileCreated is the FileOperation when a file is created
+var FileCreated = core.FileCreated
+
+// FileModified is the FileOperation when a file is modified
+var FileModified = core.FileModified
+
+// FileDeleted is the FileOperation when a file is deleted
+var FileDeleted = core.FileDeleted
+
+// FileRenamed is the FileOperation when a file is renamed or moved
+var FileRenamed = core.FileRenamed
+
+// FileAttributes is the FileOperation when a file's attributes change
+var FileAttributes = core.FileAttributes
+
+// FileModifiedWatch is a Watch that observes when a file modified time changes,
+// is created or is deleted
 type FileModifiedWatch struct {
 	fileName         string
+	exists           bool
 	lastModifiedTime time.Time
+	hash             *contentHash
 }
 
-// NewFileModifiedWatch creates a new FileModifiedWatch for the provided file
+// NewFileModifiedWatch creates a new FileModifiedWatch for the provided file.
+// The file does not need to exist yet
 func NewFileModifiedWatch(file string) *FileModifiedWatch {
 	watch := new(FileModifiedWatch)
 	watch.fileName = file
@@ -29,31 +61,122 @@ func NewFileModifiedWatch(file string) *FileModifiedWatch {
 	var info, err = os.Stat(watch.fileName)
 
 	if err != nil {
-		fmt.Println("Failed to read file", watch.fileName, "err", err)
-		return nil
+		log.Debug("Failed to read file %s : %s", watch.fileName, err)
+		return watch
 	}
 
+	watch.exists = true
 	watch.lastModifiedTime = info.ModTime()
 	return watch
 }
 
+// NewFileContentWatch creates a new FileModifiedWatch that observes when the
+// content of a file changes, using a SHA-256 digest of up to maxBytes of the
+// file (or all of it if maxBytes <= 0). If diff is true, events for text files
+// include a unified diff of the change
+func NewFileContentWatch(file string, maxBytes int64,
+	diff bool) *FileModifiedWatch {
+	watch := NewFileModifiedWatch(file)
+	watch.hash = newContentHash(maxBytes, diff)
+
+	if watch.exists {
+		if _, err := watch.hashFile(); err != nil {
+			log.Debug("Failed to hash file %s : %s", file, err)
+		}
+	}
+
+	return watch
+}
+
 // Observe whether a file has been modified since it was last observed
 func (watch *FileModifiedWatch) Observe() *core.WatchEvent {
+	return observe(watch.fileName, watch)
+}
+
+// ObserveContext observes whether a file has been modified since it was last
+// observed, returning an error if the file could not be read
+func (watch *FileModifiedWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Get file information
 	var info, err = os.Stat(watch.fileName)
 
-	if err != nil {
-		fmt.Println("Failed to read file", watch.fileName, "err", err)
-		return nil
+	if os.IsNotExist(err) {
+		if watch.exists {
+			watch.exists = false
+			if watch.hash != nil {
+				watch.hash.reset()
+			}
+			return watch.event(watch.lastModifiedTime, FileDeleted), nil
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, core.NewWatchError(watch.fileName, err)
+	}
+
+	if watch.hash != nil {
+		return watch.observeContent(info)
+	}
+
+	if !watch.exists {
+		watch.exists = true
+		watch.lastModifiedTime = info.ModTime()
+		return watch.event(info.ModTime(), FileCreated), nil
 	}
 
 	if info.ModTime() != watch.lastModifiedTime {
 		watch.lastModifiedTime = info.ModTime()
-		return core.NewWatchEvent(map[string]interface{}{
-			FileName:    watch.fileName,
-			FileModTime: info.ModTime(),
-		})
+		return watch.event(info.ModTime(), FileModified), nil
 	}
 
-	return nil
+	return nil, nil
+}
+
+// observeContent observes whether the content of the file has changed
+func (watch *FileModifiedWatch) observeContent(info os.FileInfo) (*core.WatchEvent, error) {
+	var change, err = watch.hashFile()
+	if err != nil {
+		return nil, core.NewWatchError(watch.fileName, err)
+	}
+
+	var operation = FileModified
+	if !watch.exists {
+		operation = FileCreated
+	}
+	watch.exists = true
+	watch.lastModifiedTime = info.ModTime()
+
+	if change == nil {
+		return nil, nil
+	}
+
+	var event = watch.event(info.ModTime(), operation)
+	event.Data[FileDigest] = change.digest
+	event.Data[FilePreviousDigest] = change.previousDigest
+	if len(change.diff) > 0 {
+		event.Data[FileDiff] = change.diff
+	}
+	return event, nil
+}
+
+// hashFile updates the hash of the file content
+func (watch *FileModifiedWatch) hashFile() (*contentChange, error) {
+	var file, err = os.Open(watch.fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return watch.hash.update(watch.fileName, file)
+}
+
+func (watch *FileModifiedWatch) event(modTime time.Time,
+	operation string) *core.WatchEvent {
+	return core.NewWatchEvent(map[string]interface{}{
+		FileName:      watch.fileName,
+		FileModTime:   modTime,
+		FileOperation: operation,
+	})
 }
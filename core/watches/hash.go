@@ -0,0 +1,65 @@
+package watches
+
+import (
+	"io"
+
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// contentHash detects changes in content using its SHA-256 digest
+type contentHash struct {
+	maxBytes int64
+	diff     bool
+	digest   string
+	content  []byte
+}
+
+// contentChange is a change in content found by a contentHash
+type contentChange struct {
+	digest         string
+	previousDigest string
+	diff           string
+}
+
+func newContentHash(maxBytes int64, diff bool) *contentHash {
+	hash := new(contentHash)
+	hash.maxBytes = maxBytes
+	hash.diff = diff
+	return hash
+}
+
+// update the hash with the latest content, returning the change if the content
+// is different to the last time it was hashed
+func (hash *contentHash) update(name string,
+	reader io.Reader) (*contentChange, error) {
+	var digest, content, err = utils.HashContent(reader, hash.maxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if digest == hash.digest {
+		return nil, nil
+	}
+
+	var change = new(contentChange)
+	change.digest = digest
+	change.previousDigest = hash.digest
+
+	if hash.diff && utils.IsText(content) && utils.IsText(hash.content) {
+		change.diff = utils.UnifiedDiff(name, name, string(hash.content),
+			string(content))
+	}
+
+	hash.digest = digest
+	if hash.diff {
+		hash.content = content
+	}
+
+	return change, nil
+}
+
+// reset the hash so the next content is always a change
+func (hash *contentHash) reset() {
+	hash.digest = ""
+	hash.content = nil
+}
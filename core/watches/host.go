@@ -0,0 +1,582 @@
+package watches
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// HostExceeded is a key in WatchEvent for the host thresholds that were
+// exceeded
+var HostExceeded = "host.exceeded"
+
+// The keys in WatchEvent for the host load averages
+var (
+	HostLoad1  = "host.load1"
+	HostLoad5  = "host.load5"
+	HostLoad15 = "host.load15"
+)
+
+// HostLoadThresholds are the load averages above which a HostLoadWatch
+// reports the host. Thresholds that are 0 are not checked
+type HostLoadThresholds struct {
+	Load1  float64
+	Load5  float64
+	Load15 float64
+	// PerCore divides the load averages by the number of cores before they
+	// are compared with the thresholds
+	PerCore bool
+}
+
+// HostLoadWatch is a Watch that observes the load averages of the host
+type HostLoadWatch struct {
+	thresholds HostLoadThresholds
+	fs         procfs.FS
+}
+
+// NewHostLoadWatch creates a new HostLoadWatch that reports when the load
+// averages exceed the thresholds
+func NewHostLoadWatch(fs procfs.FS,
+	thresholds HostLoadThresholds) *HostLoadWatch {
+	watch := new(HostLoadWatch)
+	watch.thresholds = thresholds
+	watch.fs = fs
+	return watch
+}
+
+// Observe whether the host load is high
+func (watch *HostLoadWatch) Observe() *core.WatchEvent {
+	return observe("host load", watch)
+}
+
+// ObserveContext observes the load averages of the host, returning an event
+// if they exceed the thresholds
+func (watch *HostLoadWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var load, err = watch.fs.LoadAvg()
+	if err != nil {
+		return nil, core.NewWatchError("host load", err)
+	}
+
+	var loads = map[string]float64{
+		HostLoad1:  load.Load1,
+		HostLoad5:  load.Load5,
+		HostLoad15: load.Load15,
+	}
+
+	var cores = 1
+	if watch.thresholds.PerCore {
+		var stat, statErr = watch.fs.Stat()
+		if statErr != nil {
+			return nil, core.NewWatchError("host load", statErr)
+		}
+		if len(stat.CPUs) > 0 {
+			cores = len(stat.CPUs)
+		}
+	}
+
+	var exceeded = exceededThresholds(map[string][2]float64{
+		HostLoad1:  {loads[HostLoad1] / float64(cores), watch.thresholds.Load1},
+		HostLoad5:  {loads[HostLoad5] / float64(cores), watch.thresholds.Load5},
+		HostLoad15: {loads[HostLoad15] / float64(cores), watch.thresholds.Load15},
+	})
+
+	log.Debug("load1=%f load5=%f load15=%f cores=%d", load.Load1, load.Load5,
+		load.Load15, cores)
+
+	if len(exceeded) == 0 {
+		// Nothing to report
+		return nil, nil
+	}
+
+	var data = map[string]interface{}{
+		HostExceeded: exceeded,
+	}
+	for key, value := range loads {
+		data[key] = value
+	}
+	return core.NewWatchEvent(data), nil
+}
+
+// The keys in WatchEvent for the host memory and swap usage. Sizes are in
+// bytes and swapping is in pages per second
+var (
+	HostMemTotal      = "host.mem_total"
+	HostMemAvailable  = "host.mem_available"
+	HostMemUsedRatio  = "host.mem_used_ratio"
+	HostSwapTotal     = "host.swap_total"
+	HostSwapUsed      = "host.swap_used"
+	HostSwapUsedRatio = "host.swap_used_ratio"
+	HostSwapInPerSec  = "host.swap_in_per_sec"
+	HostSwapOutPerSec = "host.swap_out_per_sec"
+)
+
+// HostMemoryThresholds are the values above which a HostMemoryWatch reports
+// the host. Thresholds that are 0 are not checked
+type HostMemoryThresholds struct {
+	// MemUsedRatio is the fraction of memory that is not available
+	MemUsedRatio  float64
+	SwapUsedRatio float64
+	SwapInPerSec  float64
+	SwapOutPerSec float64
+}
+
+// HostMemoryWatch is a Watch that observes the memory and swap usage of the
+// host
+type HostMemoryWatch struct {
+	thresholds      HostMemoryThresholds
+	fs              procfs.FS
+	swapIn          uint64
+	swapOut         uint64
+	lastObservation time.Time
+}
+
+// NewHostMemoryWatch creates a new HostMemoryWatch that reports when the
+// memory or swap usage exceeds the thresholds
+func NewHostMemoryWatch(fs procfs.FS,
+	thresholds HostMemoryThresholds) *HostMemoryWatch {
+	watch := new(HostMemoryWatch)
+	watch.thresholds = thresholds
+	watch.fs = fs
+
+	// Init the watch with an initial value
+	watch.Observe()
+
+	return watch
+}
+
+// Observe whether the host is short of memory
+func (watch *HostMemoryWatch) Observe() *core.WatchEvent {
+	return observe("host memory", watch)
+}
+
+// ObserveContext observes the memory usage of the host, returning an event if
+// it or the swapping since the last observation exceed the thresholds
+func (watch *HostMemoryWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var info, err = watch.fs.MemInfo()
+	var vmstat map[string]uint64
+	if err == nil {
+		vmstat, err = watch.fs.VMStat()
+	}
+	if err != nil {
+		return nil, core.NewWatchError("host memory", err)
+	}
+
+	var data = map[string]interface{}{
+		HostMemTotal:     info.MemTotal,
+		HostMemAvailable: info.MemAvailable,
+		HostSwapTotal:    info.SwapTotal,
+		HostSwapUsed:     info.SwapTotal - info.SwapFree,
+	}
+
+	var memUsedRatio, swapUsedRatio float64
+	if info.MemTotal > 0 {
+		memUsedRatio = 1 - float64(info.MemAvailable)/float64(info.MemTotal)
+	}
+	if info.SwapTotal > 0 {
+		swapUsedRatio = float64(info.SwapTotal-info.SwapFree) /
+			float64(info.SwapTotal)
+	}
+	data[HostMemUsedRatio] = memUsedRatio
+	data[HostSwapUsedRatio] = swapUsedRatio
+
+	var now = time.Now()
+	var since = now.Sub(watch.lastObservation).Seconds()
+	var firstObservation = watch.lastObservation.IsZero()
+	var lastSwapIn, lastSwapOut = watch.swapIn, watch.swapOut
+
+	watch.swapIn = vmstat["pswpin"]
+	watch.swapOut = vmstat["pswpout"]
+	watch.lastObservation = now
+
+	if firstObservation || since <= 0 {
+		// Nothing to compare with yet
+		return nil, nil
+	}
+
+	var swapInPerSec = float64(watch.swapIn-lastSwapIn) / since
+	var swapOutPerSec = float64(watch.swapOut-lastSwapOut) / since
+	data[HostSwapInPerSec] = swapInPerSec
+	data[HostSwapOutPerSec] = swapOutPerSec
+
+	log.Debug("mem used=%f swap used=%f swapin/s=%f swapout/s=%f",
+		memUsedRatio, swapUsedRatio, swapInPerSec, swapOutPerSec)
+
+	var exceeded = exceededThresholds(map[string][2]float64{
+		HostMemUsedRatio:  {memUsedRatio, watch.thresholds.MemUsedRatio},
+		HostSwapUsedRatio: {swapUsedRatio, watch.thresholds.SwapUsedRatio},
+		HostSwapInPerSec:  {swapInPerSec, watch.thresholds.SwapInPerSec},
+		HostSwapOutPerSec: {swapOutPerSec, watch.thresholds.SwapOutPerSec},
+	})
+
+	if len(exceeded) == 0 {
+		// Nothing to report
+		return nil, nil
+	}
+
+	data[HostExceeded] = exceeded
+	return core.NewWatchEvent(data), nil
+}
+
+// The keys in WatchEvent for the host CPU usage over the interval since it was
+// last observed, as percentages of the time of all the cores. HostCPUCores
+// is a map of the usage of each core by its number
+var (
+	HostCPU            = "host.cpu"
+	HostCPUIOWait      = "host.cpu_iowait"
+	HostCPUSteal       = "host.cpu_steal"
+	HostCPUCores       = "host.cpu_cores"
+	HostCPUBusiestCore = "host.cpu_busiest_core"
+)
+
+// HostCPUThresholds are the percentages above which a HostCPUWatch reports
+// the host. Thresholds that are 0 are not checked
+type HostCPUThresholds struct {
+	// CPU is the usage of all the cores together
+	CPU float64
+	// Core is the usage of any one core
+	Core   float64
+	IOWait float64
+	Steal  float64
+}
+
+// HostCPUWatch is a Watch that observes the CPU usage of the host and each of
+// its cores
+type HostCPUWatch struct {
+	thresholds HostCPUThresholds
+	fs         procfs.FS
+	last       *procfs.SystemStat
+}
+
+// NewHostCPUWatch creates a new HostCPUWatch that reports when the CPU usage
+// exceeds the thresholds
+func NewHostCPUWatch(fs procfs.FS, thresholds HostCPUThresholds) *HostCPUWatch {
+	watch := new(HostCPUWatch)
+	watch.thresholds = thresholds
+	watch.fs = fs
+
+	// Init the watch with an initial value
+	watch.Observe()
+
+	return watch
+}
+
+// Observe whether the host CPU is busy
+func (watch *HostCPUWatch) Observe() *core.WatchEvent {
+	return observe("host cpu", watch)
+}
+
+// ObserveContext observes the CPU usage of the host, returning an event if the
+// usage since the last observation exceeds the thresholds
+func (watch *HostCPUWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var stat, err = watch.fs.Stat()
+	if err != nil {
+		return nil, core.NewWatchError("host cpu", err)
+	}
+
+	var last = watch.last
+	watch.last = &stat
+
+	if last == nil {
+		// Nothing to compare with yet
+		return nil, nil
+	}
+
+	// percent gets the share of the time between the observations that a CPU
+	// spent in a mode
+	var percent = func(mode func(procfs.CPUTimes) uint64, times procfs.CPUTimes,
+		lastTimes procfs.CPUTimes) float64 {
+		var total = int64(times.Total()) - int64(lastTimes.Total())
+		if total <= 0 {
+			return 0
+		}
+		return float64(int64(mode(times))-int64(mode(lastTimes))) /
+			float64(total) * 100
+	}
+	var busy = func(times procfs.CPUTimes) uint64 { return times.Busy() }
+	var iowait = func(times procfs.CPUTimes) uint64 { return times.IOWait }
+	var steal = func(times procfs.CPUTimes) uint64 { return times.Steal }
+
+	var cpu = percent(busy, stat.CPU, last.CPU)
+	var data = map[string]interface{}{
+		HostCPU:       cpu,
+		HostCPUIOWait: percent(iowait, stat.CPU, last.CPU),
+		HostCPUSteal:  percent(steal, stat.CPU, last.CPU),
+	}
+
+	var cores = make(map[int]float64)
+	var busiestCore float64
+	for number, times := range stat.CPUs {
+		if lastTimes, ok := last.CPUs[number]; ok {
+			cores[number] = percent(busy, times, lastTimes)
+			if cores[number] > busiestCore {
+				busiestCore = cores[number]
+			}
+		}
+	}
+	data[HostCPUCores] = cores
+	data[HostCPUBusiestCore] = busiestCore
+
+	log.Debug("cpu=%f busiest core=%f iowait=%f steal=%f", cpu, busiestCore,
+		data[HostCPUIOWait], data[HostCPUSteal])
+
+	var exceeded = exceededThresholds(map[string][2]float64{
+		HostCPU:            {cpu, watch.thresholds.CPU},
+		HostCPUBusiestCore: {busiestCore, watch.thresholds.Core},
+		HostCPUIOWait:      {data[HostCPUIOWait].(float64), watch.thresholds.IOWait},
+		HostCPUSteal:       {data[HostCPUSteal].(float64), watch.thresholds.Steal},
+	})
+
+	if len(exceeded) == 0 {
+		// Nothing to report
+		return nil, nil
+	}
+
+	data[HostExceeded] = exceeded
+	return core.NewWatchEvent(data), nil
+}
+
+// The keys in WatchEvent for the percentage of the last 10 seconds that some
+// tasks on the host were stalled waiting for a resource
+var (
+	HostCPUPressure    = "host.cpu_pressure"
+	HostMemoryPressure = "host.memory_pressure"
+	HostIOPressure     = "host.io_pressure"
+)
+
+// The keys in WatchEvent for the percentage of the last 10 seconds that all
+// the non-idle tasks on the host were stalled waiting for a resource
+var (
+	HostMemoryFullPressure = "host.memory_full_pressure"
+	HostIOFullPressure     = "host.io_full_pressure"
+)
+
+// HostPressureThresholds are the pressures above which a HostPressureWatch
+// reports the host. Thresholds that are 0 are not checked
+type HostPressureThresholds struct {
+	CPU        float64
+	Memory     float64
+	IO         float64
+	MemoryFull float64
+	IOFull     float64
+}
+
+// HostPressureWatch is a Watch that observes the pressure stall information of
+// the host, which needs a kernel with CONFIG_PSI
+type HostPressureWatch struct {
+	thresholds HostPressureThresholds
+	fs         procfs.FS
+}
+
+// NewHostPressureWatch creates a new HostPressureWatch that reports when the
+// pressures exceed the thresholds
+func NewHostPressureWatch(fs procfs.FS,
+	thresholds HostPressureThresholds) *HostPressureWatch {
+	watch := new(HostPressureWatch)
+	watch.thresholds = thresholds
+	watch.fs = fs
+	return watch
+}
+
+// Observe whether the host is under pressure
+func (watch *HostPressureWatch) Observe() *core.WatchEvent {
+	return observe("host pressure", watch)
+}
+
+// ObserveContext observes the pressure stall information of the host,
+// returning an event if it exceeds the thresholds
+func (watch *HostPressureWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var pressures = make(map[string]procfs.Pressure)
+	for _, resource := range []string{procfs.PressureCPU,
+		procfs.PressureMemory, procfs.PressureIO} {
+		var pressure, err = watch.fs.Pressure(resource)
+		if err != nil {
+			return nil, core.NewWatchError("host pressure", err)
+		}
+		pressures[resource] = pressure
+	}
+
+	var values = map[string]float64{
+		HostCPUPressure:        pressures[procfs.PressureCPU].Some.Avg10,
+		HostMemoryPressure:     pressures[procfs.PressureMemory].Some.Avg10,
+		HostIOPressure:         pressures[procfs.PressureIO].Some.Avg10,
+		HostMemoryFullPressure: pressures[procfs.PressureMemory].Full.Avg10,
+		HostIOFullPressure:     pressures[procfs.PressureIO].Full.Avg10,
+	}
+
+	var exceeded = exceededThresholds(map[string][2]float64{
+		HostCPUPressure:        {values[HostCPUPressure], watch.thresholds.CPU},
+		HostMemoryPressure:     {values[HostMemoryPressure], watch.thresholds.Memory},
+		HostIOPressure:         {values[HostIOPressure], watch.thresholds.IO},
+		HostMemoryFullPressure: {values[HostMemoryFullPressure], watch.thresholds.MemoryFull},
+		HostIOFullPressure:     {values[HostIOFullPressure], watch.thresholds.IOFull},
+	})
+
+	if len(exceeded) == 0 {
+		// Nothing to report
+		return nil, nil
+	}
+
+	var data = map[string]interface{}{
+		HostExceeded: exceeded,
+	}
+	for key, value := range values {
+		data[key] = value
+	}
+	return core.NewWatchEvent(data), nil
+}
+
+// HostUptime is a key in WatchEvent for how long the host has been up
+var HostUptime = "host.uptime"
+
+// HostBootID is a key in WatchEvent for the random id the kernel generated
+// when the host booted
+var HostBootID = "host.boot_id"
+
+// HostPreviousBootID is a key in WatchEvent for the boot id of the host before
+// it was rebooted
+var HostPreviousBootID = "host.previous_boot_id"
+
+// HostBootTime is a key in WatchEvent for when the host booted
+var HostBootTime = "host.boottime"
+
+// HostPreviousBootTime is a key in WatchEvent for when the host booted before
+// it was rebooted, if it is known
+var HostPreviousBootTime = "host.previous_boottime"
+
+// HostRebootWatch is a Watch that observes when the host is rebooted. Reboots
+// are found from the boot id, as the boot time can move when the clock is set
+type HostRebootWatch struct {
+	fs        procfs.FS
+	stateFile string
+	bootID    string
+	bootTime  time.Time
+}
+
+// NewHostRebootWatch creates a new HostRebootWatch for the host of the proc
+// filesystem. If stateFile is not empty the boot id is saved in it, so reboots
+// while clockwork was not running are reported when it starts again
+func NewHostRebootWatch(fs procfs.FS, stateFile string) *HostRebootWatch {
+	watch := new(HostRebootWatch)
+	watch.fs = fs
+	watch.stateFile = stateFile
+	watch.loadState()
+	return watch
+}
+
+// Observe whether the host has rebooted
+func (watch *HostRebootWatch) Observe() *core.WatchEvent {
+	return observe("host reboot", watch)
+}
+
+// ObserveContext observes the boot id of the host, returning an event if it
+// has changed since the last observation
+func (watch *HostRebootWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var bootID, err = watch.fs.BootID()
+	if err != nil {
+		return nil, core.NewWatchError("host reboot", err)
+	}
+
+	var lastBootID = watch.bootID
+	if bootID == lastBootID {
+		// Nothing to report
+		return nil, nil
+	}
+
+	var lastBootTime = watch.bootTime
+	watch.bootID = bootID
+	watch.bootTime = time.Time{}
+	if stat, statErr := watch.fs.Stat(); statErr == nil && stat.BootTime > 0 {
+		watch.bootTime = time.Unix(stat.BootTime, 0)
+	}
+	watch.saveState()
+
+	if len(lastBootID) == 0 {
+		// Nothing to compare with yet
+		return nil, nil
+	}
+
+	var data = map[string]interface{}{
+		HostBootID:         bootID,
+		HostPreviousBootID: lastBootID,
+	}
+	if uptime, uptimeErr := watch.fs.Uptime(); uptimeErr == nil {
+		data[HostUptime] = time.Duration(uptime * float64(time.Second))
+	}
+	if !watch.bootTime.IsZero() {
+		data[HostBootTime] = watch.bootTime
+	}
+	if !lastBootTime.IsZero() {
+		data[HostPreviousBootTime] = lastBootTime
+	}
+	return core.NewWatchEvent(data), nil
+}
+
+// loadState reads the boot id and time saved in the state file, if there is
+// one
+func (watch *HostRebootWatch) loadState() {
+	if len(watch.stateFile) == 0 {
+		return
+	}
+
+	var state, err = utils.GetFileAsString(watch.stateFile)
+	if err != nil {
+		return
+	}
+
+	var fields = strings.Fields(state)
+	if len(fields) != 2 {
+		log.Warn("Invalid boot state in %s", watch.stateFile)
+		return
+	}
+	var seconds, parseErr = strconv.ParseInt(fields[1], 10, 64)
+	if parseErr != nil {
+		log.Warn("Invalid boot time in %s : %s", watch.stateFile, parseErr)
+		return
+	}
+
+	watch.bootID = fields[0]
+	if seconds > 0 {
+		watch.bootTime = time.Unix(seconds, 0)
+	}
+}
+
+// saveState writes the boot id and time to the state file
+func (watch *HostRebootWatch) saveState() {
+	if len(watch.stateFile) == 0 {
+		return
+	}
+
+	var seconds int64
+	if !watch.bootTime.IsZero() {
+		seconds = watch.bootTime.Unix()
+	}
+	var state = watch.bootID + " " + strconv.FormatInt(seconds, 10) + "\n"
+	if err := utils.WriteFileAtomic(watch.stateFile, []byte(state), 0644); err != nil {
+		log.Warn("Failed to save boot id to %s : %s", watch.stateFile, err)
+	}
+}
@@ -0,0 +1,80 @@
+package watches
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+func TestHostRebootWatch(t *testing.T) {
+	var fs = procfs.NewFS(writeFixture(t, map[string]string{
+		"sys/kernel/random/boot_id": "6f1c6a4e-0000-4000-8000-000000000001\n",
+		"stat":                      "cpu  1 0 1 10 0 0 0 0 0 0\nbtime 1700000000\n",
+		"uptime":                    "120.00 200.00\n",
+	}))
+	var stateFile = filepath.Join(t.TempDir(), "boot")
+
+	var boot = func(id string, btime string) {
+		if err := ioutil.WriteFile(fs.Path("sys/kernel/random/boot_id"),
+			[]byte(id+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fs.Path("stat"),
+			[]byte("cpu  1 0 1 10 0 0 0 0 0 0\nbtime "+btime+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing is reported the first time, as there is nothing to compare with
+	var watch = NewHostRebootWatch(fs, stateFile)
+	if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+		t.Fatalf("expected nothing on the first observation, got %v %v", event, err)
+	}
+
+	// Setting the clock moves the boot time, which isn't a reboot
+	boot("6f1c6a4e-0000-4000-8000-000000000001", "1700000005")
+	if event, _ := watch.ObserveContext(context.Background()); event != nil {
+		t.Fatalf("expected no event when the boot time moves, got %v", event.Data)
+	}
+
+	// A reboot while clockwork was not running is found from the state file
+	boot("6f1c6a4e-0000-4000-8000-000000000002", "1700086400")
+	watch = NewHostRebootWatch(fs, stateFile)
+	var event, err = watch.ObserveContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event == nil {
+		t.Fatal("expected the reboot to be reported")
+	}
+	var expected = map[string]interface{}{
+		HostBootID:           "6f1c6a4e-0000-4000-8000-000000000002",
+		HostPreviousBootID:   "6f1c6a4e-0000-4000-8000-000000000001",
+		HostBootTime:         time.Unix(1700086400, 0),
+		HostPreviousBootTime: time.Unix(1700000000, 0),
+		HostUptime:           2 * time.Minute,
+	}
+	if !reflect.DeepEqual(event.Data, expected) {
+		t.Errorf("expected %v, got %v", expected, event.Data)
+	}
+
+	if event, _ = watch.ObserveContext(context.Background()); event != nil {
+		t.Errorf("expected the reboot to be reported once, got %v", event.Data)
+	}
+	var state, _ = ioutil.ReadFile(stateFile)
+	if string(state) != "6f1c6a4e-0000-4000-8000-000000000002 1700086400\n" {
+		t.Errorf("expected the new boot to be saved, got %q", state)
+	}
+}
+
+func TestHostRebootWatchWithoutBootID(t *testing.T) {
+	var watch = NewHostRebootWatch(procfs.NewFS(t.TempDir()), "")
+	if _, err := watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error when there is no boot id")
+	}
+}
@@ -0,0 +1,323 @@
+package watches
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// URLStatus is a key in WatchEvent for the status code of an HTTP response
+var URLStatus = "url.status"
+
+// URLLatency is a key in WatchEvent for how long an HTTP request took
+var URLLatency = "url.latency"
+
+// URLHealthy is a key in WatchEvent for whether an HTTP check passed
+var URLHealthy = "url.healthy"
+
+// URLAssertion is a key in WatchEvent for the HTTP check assertion that failed
+var URLAssertion = "url.assertion"
+
+// URLAssertionMessage is a key in WatchEvent for why an HTTP check assertion
+// failed
+var URLAssertionMessage = "url.assertion.message"
+
+// URLRecovered is a key in WatchEvent for the HTTP check assertion that was
+// failing before the check passed again
+var URLRecovered = "url.recovered"
+
+// The assertions that can fail in an HTTPCheck
+var (
+	AssertRequest      = "request"
+	AssertStatus       = "status"
+	AssertLatency      = "latency"
+	AssertBodyContains = "body.contains"
+	AssertBodyRegex    = "body.regex"
+	AssertJSONPath     = "body.jsonpath"
+)
+
+// defaultHTTPTimeout is used when an HTTPCheck has no timeout
+var defaultHTTPTimeout = 10 * time.Second
+
+// defaultMaxRedirects is used when an HTTPCheck does not limit redirects
+var defaultMaxRedirects = 10
+
+// maxCheckBodyBytes limits how much of a response body is checked
+var maxCheckBodyBytes = int64(1024 * 1024)
+
+// HTTPCheck describes the request made by an HTTPCheckWatch and what the
+// response must look like for the check to pass
+type HTTPCheck struct {
+	Method  string
+	Headers map[string]string
+	Body    string
+	Timeout time.Duration
+
+	// ExpectedStatus is the allowed status codes, any 2xx if empty
+	ExpectedStatus []int
+	// MaxLatency is the longest the request can take, unlimited if 0
+	MaxLatency time.Duration
+	// BodyContains must be in the response body if set
+	BodyContains string
+	// BodyRegex must match the response body if set
+	BodyRegex string
+	// JSONPath must exist in the JSON response body if set
+	JSONPath string
+	// JSONValue is the value expected at JSONPath if set
+	JSONValue string
+
+	// NoRedirects stops redirects being followed
+	NoRedirects bool
+	// MaxRedirects limits how many redirects are followed
+	MaxRedirects int
+
+	// CAFile is a PEM file of the CAs trusted for TLS
+	CAFile string
+	// CertFile and KeyFile are a client certificate to use for TLS
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool
+}
+
+// HTTPCheckWatch is a Watch that checks the health of an HTTP endpoint. It
+// produces an event when the check starts failing, when a different assertion
+// fails and when it passes again, rather than each time it is observed
+type HTTPCheckWatch struct {
+	url       string
+	check     HTTPCheck
+	client    *http.Client
+	bodyRegex *regexp.Regexp
+	problem   string
+}
+
+// NewHTTPCheckWatch creates a new HTTPCheckWatch for the provided url and check
+func NewHTTPCheckWatch(url string, check HTTPCheck) *HTTPCheckWatch {
+	watch := new(HTTPCheckWatch)
+	watch.url = url
+	watch.check = check
+
+	if len(watch.check.Method) == 0 {
+		watch.check.Method = http.MethodGet
+	}
+	if watch.check.Timeout <= 0 {
+		watch.check.Timeout = defaultHTTPTimeout
+	}
+	if watch.check.MaxRedirects <= 0 {
+		watch.check.MaxRedirects = defaultMaxRedirects
+	}
+
+	if len(check.BodyRegex) > 0 {
+		var re, err = regexp.Compile(check.BodyRegex)
+		if err != nil {
+			log.Error("Invalid body regex %s : %s", check.BodyRegex, err)
+			return nil
+		}
+		watch.bodyRegex = re
+	}
+
+	var tlsConfig, err = httpTLSConfig(check)
+	if err != nil {
+		log.Error("Invalid TLS settings for %s : %s", url, err)
+		return nil
+	}
+
+	var transport = http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	watch.client = &http.Client{
+		Transport:     transport,
+		Timeout:       watch.check.Timeout,
+		CheckRedirect: watch.checkRedirect,
+	}
+
+	return watch
+}
+
+// httpTLSConfig creates the TLS config for a check
+func httpTLSConfig(check HTTPCheck) (*tls.Config, error) {
+	var config = new(tls.Config)
+	config.InsecureSkipVerify = check.InsecureSkipVerify
+
+	if len(check.CAFile) > 0 {
+		var pem, err = ioutil.ReadFile(check.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", check.CAFile)
+		}
+	}
+
+	if len(check.CertFile) > 0 {
+		var cert, err = tls.LoadX509KeyPair(check.CertFile, check.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// checkRedirect applies the redirect policy of the check
+func (watch *HTTPCheckWatch) checkRedirect(req *http.Request,
+	via []*http.Request) error {
+	if watch.check.NoRedirects {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= watch.check.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", len(via))
+	}
+	return nil
+}
+
+// Observe whether the HTTP check fails
+func (watch *HTTPCheckWatch) Observe() *core.WatchEvent {
+	return observe(watch.url, watch)
+}
+
+// ObserveContext runs the HTTP check, returning an event if it has started
+// failing, is failing a different assertion or has started passing again
+func (watch *HTTPCheckWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	var body = strings.NewReader(watch.check.Body)
+	req, err := http.NewRequestWithContext(ctx, watch.check.Method, watch.url,
+		body)
+	if err != nil {
+		return nil, core.NewWatchError(watch.url, err)
+	}
+	for name, value := range watch.check.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+
+	var data = map[string]interface{}{
+		URLName: watch.url,
+	}
+
+	var start = time.Now()
+	resp, err := watch.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return watch.failed(data, AssertRequest, err.Error()), nil
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxCheckBodyBytes))
+	var latency = time.Since(start)
+
+	data[URLStatus] = resp.StatusCode
+	data[URLLatency] = latency
+
+	if err != nil {
+		return watch.failed(data, AssertRequest, err.Error()), nil
+	}
+
+	if assertion, message := watch.assert(resp.StatusCode, latency,
+		respBody); len(assertion) > 0 {
+		return watch.failed(data, assertion, message), nil
+	}
+
+	// Problems are only reported when they start and when they end
+	if len(watch.problem) > 0 {
+		data[URLHealthy] = true
+		data[URLRecovered] = watch.problem
+		watch.problem = ""
+		return core.NewWatchEvent(data), nil
+	}
+
+	return nil, nil
+}
+
+// assert the response passes the check, returning the failed assertion and a
+// message if not
+func (watch *HTTPCheckWatch) assert(status int, latency time.Duration,
+	body []byte) (string, string) {
+	var check = watch.check
+
+	if !watch.statusExpected(status) {
+		return AssertStatus, fmt.Sprintf("unexpected status %d", status)
+	}
+
+	if check.MaxLatency > 0 && latency > check.MaxLatency {
+		return AssertLatency, fmt.Sprintf("took %s, more than %s", latency,
+			check.MaxLatency)
+	}
+
+	if len(check.BodyContains) > 0 &&
+		!strings.Contains(string(body), check.BodyContains) {
+		return AssertBodyContains, fmt.Sprintf("body does not contain %q",
+			check.BodyContains)
+	}
+
+	if watch.bodyRegex != nil && !watch.bodyRegex.Match(body) {
+		return AssertBodyRegex, fmt.Sprintf("body does not match %s",
+			check.BodyRegex)
+	}
+
+	if len(check.JSONPath) > 0 {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return AssertJSONPath, fmt.Sprintf("body is not JSON: %s", err)
+		}
+
+		var value, err = utils.JSONPathLookup(doc, check.JSONPath)
+		if err != nil {
+			return AssertJSONPath, err.Error()
+		}
+
+		if len(check.JSONValue) > 0 && fmt.Sprint(value) != check.JSONValue {
+			return AssertJSONPath, fmt.Sprintf("%s is %v, not %s",
+				check.JSONPath, value, check.JSONValue)
+		}
+	}
+
+	return "", ""
+}
+
+// statusExpected returns true if the status is one the check expects
+func (watch *HTTPCheckWatch) statusExpected(status int) bool {
+	if len(watch.check.ExpectedStatus) == 0 {
+		return status >= 200 && status < 300
+	}
+
+	for _, expected := range watch.check.ExpectedStatus {
+		if status == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// failed creates the event for a failed check, or returns nil if the same
+// assertion failed last time
+func (watch *HTTPCheckWatch) failed(data map[string]interface{},
+	assertion string, message string) *core.WatchEvent {
+	if assertion == watch.problem {
+		return nil
+	}
+	watch.problem = assertion
+
+	data[URLHealthy] = false
+	data[URLAssertion] = assertion
+	data[URLAssertionMessage] = message
+	return core.NewWatchEvent(data)
+}
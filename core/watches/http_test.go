@@ -0,0 +1,200 @@
+package watches
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCheckServer starts a server that responds to each path with a status and
+// body, optionally after a delay
+func newCheckServer(t *testing.T) *httptest.Server {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "all good")
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not here", http.StatusNotFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "slow")
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status": "up", "checks": [{"name": "db", "ok": true}]}`)
+	})
+
+	var server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPCheckAssertions(t *testing.T) {
+	var server = newCheckServer(t)
+
+	var tests = []struct {
+		name      string
+		path      string
+		check     HTTPCheck
+		assertion string
+	}{
+		{"ok", "/ok", HTTPCheck{}, ""},
+		{"status", "/missing", HTTPCheck{}, AssertStatus},
+		{"expected status", "/missing",
+			HTTPCheck{ExpectedStatus: []int{404}}, ""},
+		{"unexpected status", "/ok",
+			HTTPCheck{ExpectedStatus: []int{201, 204}}, AssertStatus},
+		{"latency", "/slow",
+			HTTPCheck{MaxLatency: 10 * time.Millisecond}, AssertLatency},
+		{"within latency", "/slow",
+			HTTPCheck{MaxLatency: 5 * time.Second}, ""},
+		{"contains", "/ok", HTTPCheck{BodyContains: "good"}, ""},
+		{"not contains", "/ok",
+			HTTPCheck{BodyContains: "bad"}, AssertBodyContains},
+		{"regex", "/ok", HTTPCheck{BodyRegex: "^all \\w+$"}, ""},
+		{"no regex match", "/ok",
+			HTTPCheck{BodyRegex: "^good"}, AssertBodyRegex},
+		{"json path", "/json", HTTPCheck{JSONPath: "$.status"}, ""},
+		{"json value", "/json",
+			HTTPCheck{JSONPath: "$.checks[0].ok", JSONValue: "true"}, ""},
+		{"json wrong value", "/json",
+			HTTPCheck{JSONPath: "$.status", JSONValue: "down"}, AssertJSONPath},
+		{"json missing path", "/json",
+			HTTPCheck{JSONPath: "$.version"}, AssertJSONPath},
+		{"not json", "/ok", HTTPCheck{JSONPath: "$.status"}, AssertJSONPath},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var watch = NewHTTPCheckWatch(server.URL+test.path, test.check)
+			if watch == nil {
+				t.Fatal("failed to create watch")
+			}
+
+			event, err := watch.ObserveContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(test.assertion) == 0 {
+				if event != nil {
+					t.Fatalf("expected check to pass, failed %s: %s",
+						event.GetAsString(URLAssertion),
+						event.GetAsString(URLAssertionMessage))
+				}
+				return
+			}
+
+			if event == nil {
+				t.Fatalf("expected %s assertion to fail", test.assertion)
+			}
+			if healthy, _ := event.Get(URLHealthy).(bool); healthy {
+				t.Error("expected the check to be unhealthy")
+			}
+			if assertion := event.GetAsString(URLAssertion); assertion != test.assertion {
+				t.Errorf("expected %s assertion to fail, got %s: %s",
+					test.assertion, assertion,
+					event.GetAsString(URLAssertionMessage))
+			}
+			if _, ok := event.Get(URLStatus).(int); !ok {
+				t.Error("expected the status in the event")
+			}
+			if _, ok := event.Get(URLLatency).(time.Duration); !ok {
+				t.Error("expected the latency in the event")
+			}
+		})
+	}
+}
+
+func TestHTTPCheckRecovers(t *testing.T) {
+	var healthy int32
+	var server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&healthy) == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+	defer server.Close()
+
+	var watch = NewHTTPCheckWatch(server.URL, HTTPCheck{})
+
+	event, err := watch.ObserveContext(context.Background())
+	if err != nil || event == nil {
+		t.Fatalf("expected a failure event, got %v %v", event, err)
+	}
+	if status, _ := event.Get(URLStatus).(int); status != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", status)
+	}
+
+	// Failures are reported when they start, and passing is reported once
+	if event, _ = watch.ObserveContext(context.Background()); event != nil {
+		t.Errorf("expected the failure to be reported once, got %v", event.Data)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	event, err = watch.ObserveContext(context.Background())
+	if err != nil || event == nil {
+		t.Fatalf("expected a recovery event, got %v %v", event, err)
+	}
+	if passed, _ := event.Get(URLHealthy).(bool); !passed {
+		t.Error("expected the check to be healthy again")
+	}
+	if recovered := event.GetAsString(URLRecovered); recovered != AssertStatus {
+		t.Errorf("expected the status assertion to recover, got %s", recovered)
+	}
+
+	if event, _ = watch.ObserveContext(context.Background()); event != nil {
+		t.Error("expected no event while the check keeps passing")
+	}
+}
+
+func TestHTTPCheckAssertionChanges(t *testing.T) {
+	var slow int32
+	var server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&slow) == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}))
+	defer server.Close()
+
+	var watch = NewHTTPCheckWatch(server.URL,
+		HTTPCheck{MaxLatency: 10 * time.Millisecond})
+	event, _ := watch.ObserveContext(context.Background())
+	if event == nil || event.GetAsString(URLAssertion) != AssertStatus {
+		t.Fatalf("expected the status to fail, got %v", event)
+	}
+
+	// A different assertion failing is a new problem
+	atomic.StoreInt32(&slow, 1)
+	event, _ = watch.ObserveContext(context.Background())
+	if event == nil || event.GetAsString(URLAssertion) != AssertLatency {
+		t.Fatalf("expected the latency to fail, got %v", event)
+	}
+	if event, _ = watch.ObserveContext(context.Background()); event != nil {
+		t.Errorf("expected the latency failure to be reported once, got %v",
+			event.Data)
+	}
+}
+
+func TestHTTPCheckRequestFailure(t *testing.T) {
+	var server = httptest.NewServer(http.NotFoundHandler())
+	var url = server.URL
+	server.Close()
+
+	var watch = NewHTTPCheckWatch(url, HTTPCheck{Timeout: time.Second})
+	event, err := watch.ObserveContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event == nil || event.GetAsString(URLAssertion) != AssertRequest {
+		t.Fatalf("expected the request to fail, got %v", event)
+	}
+}
@@ -0,0 +1,235 @@
+package watches
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/cgroups"
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// MemTrendRSS is a key in WatchEvent for the resident memory of a process in
+// bytes
+var MemTrendRSS = "memtrend.rss"
+
+// MemTrendPSS is a key in WatchEvent for the proportional memory of a process
+// in bytes, if it could be read
+var MemTrendPSS = "memtrend.pss"
+
+// MemTrendGrowthPerSec is a key in WatchEvent for how fast the memory of a
+// process is growing in bytes per second, from the trend of the samples
+var MemTrendGrowthPerSec = "memtrend.growth_per_sec"
+
+// MemTrendMonotonic is a key in WatchEvent that is true if the memory of a
+// process has not gone down in any of the samples
+var MemTrendMonotonic = "memtrend.monotonic"
+
+// MemTrendLimit is a key in WatchEvent for the memory limit of a process in
+// bytes
+var MemTrendLimit = "memtrend.limit"
+
+// MemTrendTimeToLimit is a key in WatchEvent for how long until the memory of
+// a process reaches its limit if it keeps growing at the same rate
+var MemTrendTimeToLimit = "memtrend.time_to_limit"
+
+// MemTrendExceeded is a key in WatchEvent for the checks that were exceeded,
+// MemTrendGrowthPerSec and/or MemTrendTimeToLimit
+var MemTrendExceeded = "memtrend.exceeded"
+
+// MemoryTrendOptions are the options for how a ProcessMemoryTrendWatch finds
+// memory leaks. Options that are 0 are not checked
+type MemoryTrendOptions struct {
+	// Window is the number of samples the trend is worked out from. The
+	// watch reports nothing until it has this many samples
+	Window int
+	// UsePSS works out the trend from the proportional memory instead of the
+	// resident memory, so memory shared with other processes is split
+	// between them
+	UsePSS bool
+	// GrowthPerSec is the rate in bytes per second above which memory that
+	// only grows is reported
+	GrowthPerSec float64
+	// Limit is the memory limit in bytes. If it is 0 the memory.max of the
+	// process's cgroup is used, if it has one
+	Limit int64
+	// Horizon reports memory that is growing fast enough to reach the limit
+	// within it
+	Horizon time.Duration
+}
+
+// DefaultMemoryTrendWindow is the number of samples used if the window is
+// not set
+var DefaultMemoryTrendWindow = 10
+
+// memorySample is the memory of a process when the watch was observed
+type memorySample struct {
+	time  time.Time
+	bytes float64
+}
+
+// ProcessMemoryTrendWatch is a Watch that observes the trend of the memory of
+// a process, to find leaks before they become a problem
+type ProcessMemoryTrendWatch struct {
+	options  MemoryTrendOptions
+	proc     procfs.Proc
+	cgroupFS cgroups.FS
+	samples  []memorySample
+}
+
+// NewProcessMemoryTrendWatch creates a new ProcessMemoryTrendWatch for the
+// provided pid in the proc filesystem. The memory limit is looked up in the
+// cgroup filesystem if it is not in the options
+func NewProcessMemoryTrendWatch(procFS procfs.FS, cgroupFS cgroups.FS, pid int,
+	options MemoryTrendOptions) *ProcessMemoryTrendWatch {
+	watch := new(ProcessMemoryTrendWatch)
+	watch.options = options
+	watch.proc = procFS.Proc(pid)
+	watch.cgroupFS = cgroupFS
+
+	if watch.options.Window < 2 {
+		watch.options.Window = DefaultMemoryTrendWindow
+	}
+
+	return watch
+}
+
+// Observe whether the memory of a process is leaking
+func (watch *ProcessMemoryTrendWatch) Observe() *core.WatchEvent {
+	return observe("process memory trend", watch)
+}
+
+// ObserveContext samples the memory of a process, returning an event if the
+// trend of the samples in the window exceeds the growth rate or will reach the
+// limit within the horizon
+func (watch *ProcessMemoryTrendWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var status, err = watch.proc.Status()
+	if err != nil {
+		if !watch.proc.Exists() {
+			err = ErrProcessNotFound
+		}
+		return nil, core.NewWatchError("process memory trend", err)
+	}
+
+	var data = map[string]interface{}{
+		MemTrendRSS: int64(status.VmRSS),
+	}
+	var bytes = float64(status.VmRSS)
+
+	var rollup, rollupErr = watch.proc.SmapsRollup()
+	if rollupErr == nil {
+		data[MemTrendPSS] = int64(rollup.Pss)
+		if watch.options.UsePSS {
+			bytes = float64(rollup.Pss)
+		}
+	} else if watch.options.UsePSS {
+		return nil, core.NewWatchError("process memory trend", rollupErr)
+	}
+
+	watch.samples = append(watch.samples, memorySample{
+		time:  time.Now(),
+		bytes: bytes,
+	})
+	if len(watch.samples) > watch.options.Window {
+		watch.samples = watch.samples[len(watch.samples)-watch.options.Window:]
+	}
+
+	if len(watch.samples) < watch.options.Window {
+		// Not enough samples for a trend yet
+		return nil, nil
+	}
+
+	var growth = memoryGrowth(watch.samples)
+	var monotonic = true
+	for i := 1; i < len(watch.samples); i++ {
+		if watch.samples[i].bytes < watch.samples[i-1].bytes {
+			monotonic = false
+			break
+		}
+	}
+
+	data[MemTrendGrowthPerSec] = growth
+	data[MemTrendMonotonic] = monotonic
+
+	log.Debug("pid=%d bytes=%f growth/s=%f monotonic=%t", watch.proc.PID,
+		bytes, growth, monotonic)
+
+	var exceeded []string
+	if watch.options.GrowthPerSec > 0 && monotonic &&
+		growth > watch.options.GrowthPerSec {
+		exceeded = append(exceeded, MemTrendGrowthPerSec)
+	}
+
+	if watch.options.Horizon > 0 && growth > 0 {
+		var limit = watch.options.Limit
+		if limit <= 0 {
+			limit = watch.cgroupLimit()
+		}
+
+		if limit > 0 {
+			var timeToLimit = time.Duration(
+				math.Max(float64(limit)-bytes, 0) / growth * float64(time.Second))
+			data[MemTrendLimit] = limit
+			data[MemTrendTimeToLimit] = timeToLimit
+
+			if timeToLimit <= watch.options.Horizon {
+				exceeded = append(exceeded, MemTrendTimeToLimit)
+			}
+		}
+	}
+
+	if len(exceeded) == 0 {
+		// Nothing to report
+		return nil, nil
+	}
+
+	sort.Strings(exceeded)
+	data[MemTrendExceeded] = exceeded
+	return core.NewWatchEvent(data), nil
+}
+
+// cgroupLimit gets the memory limit of the cgroup v2 group the process is in,
+// or 0 if there is no limit
+func (watch *ProcessMemoryTrendWatch) cgroupLimit() int64 {
+	var group, err = watch.cgroupFS.GroupOf(watch.proc)
+	if err != nil {
+		log.Debug("No cgroup for pid=%d : %s", watch.proc.PID, err)
+		return 0
+	}
+
+	var limit, limitErr = group.MemoryLimit()
+	if limitErr != nil {
+		log.Debug("Failed to read memory limit of %s : %s", group.Path, limitErr)
+	}
+	if limit == cgroups.Max {
+		return 0
+	}
+	return limit
+}
+
+// memoryGrowth fits a straight line to the samples with least squares,
+// returning its slope in bytes per second
+func memoryGrowth(samples []memorySample) float64 {
+	var n = float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+
+	for _, sample := range samples {
+		var x = sample.time.Sub(samples[0].time).Seconds()
+		sumX += x
+		sumY += sample.bytes
+		sumXY += x * sample.bytes
+		sumXX += x * x
+	}
+
+	var denominator = n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
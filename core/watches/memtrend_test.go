@@ -0,0 +1,97 @@
+package watches
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core/cgroups"
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+func TestMemoryGrowth(t *testing.T) {
+	var start = time.Now()
+	var samples []memorySample
+	for i := 0; i < 5; i++ {
+		samples = append(samples, memorySample{
+			time:  start.Add(time.Duration(i) * time.Second),
+			bytes: float64(1000 + 250*i),
+		})
+	}
+
+	if growth := memoryGrowth(samples); math.Abs(growth-250) > 0.001 {
+		t.Errorf("expected growth of 250 bytes/s, got %f", growth)
+	}
+	if growth := memoryGrowth(samples[:1]); growth != 0 {
+		t.Errorf("expected no growth from one sample, got %f", growth)
+	}
+}
+
+func TestProcessMemoryTrendWatchCgroupLimit(t *testing.T) {
+	var procFS = procfs.NewFS(writeFixture(t, map[string]string{
+		"10/cgroup": "0::/app.slice/app.service\n",
+		"20/cgroup": "0::/other.slice\n",
+	}))
+	var cgroupFS = cgroups.NewFS(writeFixture(t, map[string]string{
+		"app.slice/memory.max":             "1073741824\n",
+		"app.slice/app.service/memory.max": "max\n",
+		"other.slice/memory.max":           "max\n",
+	}))
+
+	var writeRSS = func(pid int, kB int) {
+		var status = fmt.Sprintf("Name:\tapp\nVmRSS:\t%d kB\n", kB)
+		if err := ioutil.WriteFile(procFS.Path(fmt.Sprint(pid), "status"),
+			[]byte(status), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var options = MemoryTrendOptions{Window: 2, Horizon: time.Hour}
+	for _, test := range []struct {
+		pid   int
+		limit interface{}
+	}{
+		// The limit is the lowest of the group and the groups above it
+		{10, int64(1073741824)},
+		{20, nil},
+	} {
+		var watch = NewProcessMemoryTrendWatch(procFS, cgroupFS, test.pid, options)
+
+		writeRSS(test.pid, 1000)
+		if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+			t.Fatalf("pid %d: expected nothing from the first sample, got %v %v",
+				test.pid, event, err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		writeRSS(test.pid, 2000)
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.limit == nil {
+			if event != nil {
+				t.Errorf("pid %d: expected no event without a limit, got %v",
+					test.pid, event.Data)
+			}
+			continue
+		}
+		if event == nil {
+			t.Fatalf("pid %d: expected the limit to be reached within the horizon",
+				test.pid)
+		}
+		if limit := event.Get(MemTrendLimit); limit != test.limit {
+			t.Errorf("pid %d: expected limit %v, got %v", test.pid, test.limit, limit)
+		}
+		if exceeded := event.Get(MemTrendExceeded); !reflect.DeepEqual(exceeded,
+			[]string{MemTrendTimeToLimit}) {
+			t.Errorf("pid %d: expected the time to limit to be exceeded, got %v",
+				test.pid, exceeded)
+		}
+	}
+}
@@ -1,87 +1,524 @@
 package watches
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/deanydean/clockwork/core"
 	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 	"github.com/google/gopacket/pcap"
+	"github.com/google/gopacket/pcapgo"
 )
 
-var snapLen = int32(65536)
-var promisc = true
-var timeout = time.Second
+// NetPackets is a key in WatchEvent for the packets seen in an interval
+var NetPackets = "net.packets"
 
-var is_reading = bool(true)
-var not_reading = bool(false)
+// NetBytes is a key in WatchEvent for the bytes seen in an interval
+var NetBytes = "net.bytes"
+
+// NetPacketsPerSec is a key in WatchEvent for the packets per second seen in
+// an interval
+var NetPacketsPerSec = "net.packets_per_sec"
+
+// NetBytesPerSec is a key in WatchEvent for the bytes per second seen in an
+// interval
+var NetBytesPerSec = "net.bytes_per_sec"
+
+// NetInterval is a key in WatchEvent for the length of the interval
+var NetInterval = "net.interval"
+
+// NetProtocols is a key in WatchEvent for the packets seen for each protocol
+// layer in an interval
+var NetProtocols = "net.protocols"
+
+// NetTopTalkers is a key in WatchEvent for the endpoints that sent the most
+// bytes in an interval
+var NetTopTalkers = "net.toptalkers"
+
+// NetComplete is a key in WatchEvent that is true when all the packets in a
+// capture file have been read
+var NetComplete = "net.complete"
+
+// NetExceeded is a key in WatchEvent for the thresholds that were exceeded
+var NetExceeded = "net.exceeded"
+
+// defaultTopTalkers is the number of top talkers reported by default
+var defaultTopTalkers = 5
+
+// NetCaptureOptions configures how a NetWatch captures packets
+type NetCaptureOptions struct {
+	SnapLen     int32
+	Promiscuous bool
+	Timeout     time.Duration
+	// BufferSize is the capture buffer size in bytes, the system default if 0
+	BufferSize int
+	// Filter is a BPF filter expression for the packets to capture
+	Filter string
+}
+
+// DefaultNetCaptureOptions gets the options for capturing whole packets
+// promiscuously without a filter
+func DefaultNetCaptureOptions() NetCaptureOptions {
+	return NetCaptureOptions{
+		SnapLen:     65536,
+		Promiscuous: true,
+		Timeout:     time.Second,
+	}
+}
+
+// NetThresholds are the rates above which a NetWatch reports traffic. If no
+// thresholds are set every interval with traffic is reported
+type NetThresholds struct {
+	PacketsPerSec float64
+	BytesPerSec   float64
+	// TopTalkers is how many top talkers to report
+	TopTalkers int
+}
+
+// NetTalker is the traffic sent by an endpoint
+type NetTalker struct {
+	Endpoint string
+	Packets  int64
+	Bytes    int64
+}
+
+// netStats is the traffic seen since the stats were last collected
+type netStats struct {
+	lock      sync.Mutex
+	packets   int64
+	bytes     int64
+	protocols map[string]int64
+	talkers   map[string]*NetTalker
+	since     time.Time
+	first     time.Time
+	last      time.Time
+	end       time.Time
+	complete  bool
+}
+
+func newNetStats() *netStats {
+	stats := new(netStats)
+	stats.reset(time.Now())
+	return stats
+}
+
+func (stats *netStats) reset(now time.Time) {
+	stats.packets = 0
+	stats.bytes = 0
+	stats.protocols = make(map[string]int64)
+	stats.talkers = make(map[string]*NetTalker)
+	stats.since = now
+	if !stats.last.IsZero() {
+		stats.end = stats.last
+	}
+	stats.first = time.Time{}
+	stats.last = time.Time{}
+}
+
+// add a packet to the stats
+func (stats *netStats) add(packet gopacket.Packet) {
+	var length = int64(len(packet.Data()))
+	var timestamp time.Time
+	if metadata := packet.Metadata(); metadata != nil {
+		if metadata.Length > 0 {
+			length = int64(metadata.Length)
+		}
+		timestamp = metadata.Timestamp
+	}
+
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	if stats.first.IsZero() {
+		stats.first = timestamp
+	}
+	stats.last = timestamp
+
+	stats.packets++
+	stats.bytes += length
+
+	for _, layer := range packet.Layers() {
+		stats.protocols[layer.LayerType().String()]++
+	}
+
+	if network := packet.NetworkLayer(); network != nil {
+		var src = network.NetworkFlow().Src().String()
+		var talker, ok = stats.talkers[src]
+		if !ok {
+			talker = &NetTalker{Endpoint: src}
+			stats.talkers[src] = talker
+		}
+		talker.Packets++
+		talker.Bytes += length
+	}
+}
+
+// finish marks the stats as complete, no more packets will be added
+func (stats *netStats) finish() {
+	stats.lock.Lock()
+	stats.complete = true
+	stats.lock.Unlock()
+}
+
+// collect the stats into event data and start collecting again. If
+// captureTime is true the window is measured with the capture timestamps of
+// the packets, from the last packet of the previous window to the last packet
+// of this one, rather than the time since the stats were last collected. A
+// window measured in capture time has no rates unless it has at least 2
+// packets
+func (stats *netStats) collect(now time.Time, top int,
+	captureTime bool) map[string]interface{} {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	var interval = now.Sub(stats.since)
+	if captureTime {
+		var start = stats.end
+		if start.IsZero() {
+			start = stats.first
+		}
+		interval = 0
+		if stats.packets >= 2 {
+			interval = stats.last.Sub(start)
+		}
+	}
+
+	var talkers = make([]NetTalker, 0, len(stats.talkers))
+	for _, talker := range stats.talkers {
+		talkers = append(talkers, *talker)
+	}
+	sort.Slice(talkers, func(i, j int) bool {
+		return talkers[i].Bytes > talkers[j].Bytes
+	})
+	if len(talkers) > top {
+		talkers = talkers[:top]
+	}
+
+	var data = map[string]interface{}{
+		NetPackets:    stats.packets,
+		NetBytes:      stats.bytes,
+		NetProtocols:  stats.protocols,
+		NetTopTalkers: talkers,
+		NetComplete:   stats.complete,
+	}
+	if interval > 0 {
+		data[NetPacketsPerSec] = float64(stats.packets) / interval.Seconds()
+		data[NetBytesPerSec] = float64(stats.bytes) / interval.Seconds()
+		data[NetInterval] = interval
+	}
+
+	stats.reset(now)
+	return data
+}
 
 type NetWatch struct {
 	layer      *string
 	iface      *string
-	pcapHandle *pcap.Handle
+	packets    gopacket.PacketDataSource
+	closer     io.Closer
 	decoder    *gopacket.Decoder
-	isReading  *bool
+	reading    int32
+	stats      *netStats
+	thresholds NetThresholds
+	file       string
+	realtime   bool
+	stopped    bool
 }
 
+// NewNetWatch creates a NetWatch that captures whole packets promiscuously
+// from a live interface
 func NewNetWatch(layer *string, iface *string) *NetWatch {
-	netWatch := new(NetWatch)
-	netWatch.iface = iface
+	return NewNetWatchWithOptions(layer, iface, DefaultNetCaptureOptions())
+}
 
-	// Open the handle
-	var handle, err = pcap.OpenLive(*netWatch.iface, snapLen, promisc, timeout)
+// NewNetWatchWithOptions creates a NetWatch that captures packets from a live
+// interface with the provided options
+func NewNetWatchWithOptions(layer *string, iface *string,
+	options NetCaptureOptions) *NetWatch {
+	// Get the decoder first, there's no point capturing without one
+	var decoder, err = netDecoder(*layer)
 	if err != nil {
-		fmt.Printf("Failed to open pcap for %s : %s\n", *netWatch.iface, err)
+		log.Error("Unable to watch %s : %s", *iface, err)
 		return nil
 	}
-	netWatch.pcapHandle = handle
 
-	// Create a decoder
-	decoder, ok := gopacket.DecodersByLayerName[*layer]
-	if !ok {
-		fmt.Printf("Failed to get decoder for layer=%s", *layer)
+	handle, err := openNetCapture(*iface, options)
+	if err != nil {
+		log.Error("Unable to watch %s : %s", *iface, err)
+		return nil
+	}
+
+	return newNetWatch(handle, handleCloser{handle}, decoder, iface)
+}
+
+// NewNetFileWatch creates a NetWatch that reads packets from a pcap or pcapng
+// file rather than a live interface, keeping only those that match the filter
+// if there is one. If realtime is true the packets are read at the speed they
+// were captured, otherwise as fast as possible and rates are calculated using
+// the capture timestamps of the packets
+func NewNetFileWatch(layer *string, file *string, realtime bool,
+	filter string) *NetWatch {
+	var decoder, err = netDecoder(*layer)
+	if err != nil {
+		log.Error("Unable to watch %s : %s", *file, err)
+		return nil
+	}
+
+	source, err := openNetFile(*file, filter)
+	if err != nil {
+		log.Error("Unable to watch %s : %s", *file, err)
 		return nil
 	}
-	netWatch.decoder = &decoder
 
-	netWatch.isReading = &not_reading
+	netWatch := newNetWatch(source, source.file, decoder, file)
+	netWatch.file = *file
+	netWatch.realtime = realtime
 
 	return netWatch
 }
 
+// newNetWatch creates a NetWatch reading from an open source of packets
+func newNetWatch(packets gopacket.PacketDataSource, closer io.Closer,
+	decoder gopacket.Decoder, iface *string) *NetWatch {
+	netWatch := new(NetWatch)
+	netWatch.iface = iface
+	netWatch.packets = packets
+	netWatch.closer = closer
+	netWatch.decoder = &decoder
+	netWatch.stats = newNetStats()
+	netWatch.thresholds.TopTalkers = defaultTopTalkers
+
+	return netWatch
+}
+
+// openNetCapture starts capturing packets from a live interface with the
+// provided options
+func openNetCapture(iface string, options NetCaptureOptions) (*pcap.Handle,
+	error) {
+	inactive, err := pcap.NewInactiveHandle(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pcap for %s: %w", iface, err)
+	}
+	defer inactive.CleanUp()
+
+	if err = inactive.SetSnapLen(int(options.SnapLen)); err != nil {
+		return nil, fmt.Errorf("invalid snaplen %d: %w", options.SnapLen, err)
+	}
+	if err = inactive.SetPromisc(options.Promiscuous); err != nil {
+		return nil, fmt.Errorf("failed to set promiscuous mode: %w", err)
+	}
+	if err = inactive.SetTimeout(options.Timeout); err != nil {
+		return nil, fmt.Errorf("invalid timeout %s: %w", options.Timeout, err)
+	}
+	if options.BufferSize > 0 {
+		if err = inactive.SetBufferSize(options.BufferSize); err != nil {
+			return nil, fmt.Errorf("invalid buffer size %d: %w",
+				options.BufferSize, err)
+		}
+	}
+
+	handle, err := inactive.Activate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start capture on %s: %w", iface, err)
+	}
+
+	if len(options.Filter) > 0 {
+		if err = handle.SetBPFFilter(options.Filter); err != nil {
+			handle.Close()
+			return nil, fmt.Errorf("invalid filter %q: %w", options.Filter, err)
+		}
+	}
+
+	return handle, nil
+}
+
+// handleCloser closes a live capture handle
+type handleCloser struct {
+	handle *pcap.Handle
+}
+
+func (closer handleCloser) Close() error {
+	closer.handle.Close()
+	return nil
+}
+
+// netFileSource reads the packets in a pcap or pcapng file, keeping only
+// those that match its filter if it has one
+type netFileSource struct {
+	file   *os.File
+	reader gopacket.PacketDataSource
+	filter *pcap.BPF
+}
+
+// openNetFile opens a pcap or pcapng file. The file is read without libpcap,
+// which is only used to compile the filter
+func openNetFile(path string, filter string) (*netFileSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pcap file %s: %w", path, err)
+	}
+
+	source := new(netFileSource)
+	source.file = file
+
+	var linkType interface{ LinkType() layers.LinkType }
+	if reader, err := pcapgo.NewReader(file); err == nil {
+		source.reader, linkType = reader, reader
+	} else {
+		// Not a pcap file, so try pcapng
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read pcap file %s: %w", path, err)
+		}
+		ngReader, err := pcapgo.NewNgReader(file, pcapgo.DefaultNgReaderOptions)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read pcap file %s: %w", path, err)
+		}
+		source.reader, linkType = ngReader, ngReader
+	}
+
+	if len(filter) > 0 {
+		source.filter, err = pcap.NewBPF(linkType.LinkType(),
+			int(DefaultNetCaptureOptions().SnapLen), filter)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("invalid filter %q: %w", filter, err)
+		}
+	}
+
+	return source, nil
+}
+
+// ReadPacketData reads the next packet that matches the filter
+func (source *netFileSource) ReadPacketData() ([]byte, gopacket.CaptureInfo,
+	error) {
+	for {
+		data, ci, err := source.reader.ReadPacketData()
+		if err != nil || source.filter == nil || source.filter.Matches(ci, data) {
+			return data, ci, err
+		}
+	}
+}
+
+// netDecoder gets the decoder for a named layer
+func netDecoder(layer string) (gopacket.Decoder, error) {
+	decoder, ok := gopacket.DecodersByLayerName[layer]
+	if !ok {
+		return nil, fmt.Errorf("no decoder for layer=%s", layer)
+	}
+	return decoder, nil
+}
+
+// SetThresholds sets the rates above which traffic is reported
+func (watch *NetWatch) SetThresholds(thresholds NetThresholds) {
+	if thresholds.TopTalkers <= 0 {
+		thresholds.TopTalkers = defaultTopTalkers
+	}
+	watch.thresholds = thresholds
+}
+
 func (watch *NetWatch) startReading() {
-	source := gopacket.NewPacketSource(watch.pcapHandle, *watch.decoder)
+	source := gopacket.NewPacketSource(watch.packets, *watch.decoder)
 	source.Lazy = true
 	source.NoCopy = true
 	source.DecodeStreamsAsDatagrams = true
-	fmt.Fprintln(os.Stderr, "Starting to read packets")
+	log.Debug("Starting to read packets on %s", *watch.iface)
 
-	count := 0
-	bytes := int64(0)
+	var start = time.Now()
+	var first time.Time
 
 	for packet := range source.Packets() {
-		count++
-		bytes += int64(len(packet.Data()))
+		if watch.realtime {
+			// Wait until the packet is due
+			var timestamp = packet.Metadata().Timestamp
+			if first.IsZero() {
+				first = timestamp
+			}
+			time.Sleep(time.Until(start.Add(timestamp.Sub(first))))
+		}
 
-		// TODO collect stats
-		fmt.Printf("Got packet %s \n", packet)
+		watch.stats.add(packet)
 	}
 
-	fmt.Printf("Completed reading packets")
-	watch.isReading = &not_reading
+	log.Info("Completed reading packets on %s", *watch.iface)
+
+	if len(watch.file) > 0 {
+		// There won't be any more packets in the file
+		watch.stats.finish()
+		return
+	}
+	atomic.StoreInt32(&watch.reading, 0)
 }
 
 // Observe the network
 func (watch *NetWatch) Observe() *core.WatchEvent {
+	return observe("net", watch)
+}
 
-	if !*watch.isReading {
+// ObserveContext observes the traffic seen since the network was last
+// observed, returning an event if it exceeds the thresholds
+func (watch *NetWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if atomic.CompareAndSwapInt32(&watch.reading, 0, 1) {
 		// If we're not reading, start watching now...
-		watch.isReading = &is_reading
 		go watch.startReading()
+		return nil, nil
 	}
 
-	// TODO observe the stats
+	if watch.stopped {
+		// Everything in the file has been reported
+		var event = core.NewWatchEvent(map[string]interface{}{
+			NetComplete: true,
+		})
+		event.SetStatus(0, true)
+		return event, nil
+	}
 
-	return nil
+	// Packets read from a file as fast as possible can only be timed by when
+	// they were captured
+	var captureTime = len(watch.file) > 0 && !watch.realtime
+	var data = watch.stats.collect(time.Now(), watch.thresholds.TopTalkers,
+		captureTime)
+
+	var thresholds = watch.thresholds
+	var pps, _ = data[NetPacketsPerSec].(float64)
+	var bps, _ = data[NetBytesPerSec].(float64)
+	var exceeded = exceededThresholds(map[string][2]float64{
+		NetPacketsPerSec: {pps, thresholds.PacketsPerSec},
+		NetBytesPerSec:   {bps, thresholds.BytesPerSec},
+	})
+	if len(exceeded) > 0 {
+		data[NetExceeded] = exceeded
+	}
+
+	if data[NetComplete].(bool) {
+		// Always report the end of the file, then stop
+		watch.stopped = true
+		watch.closer.Close()
+		return core.NewWatchEvent(data), nil
+	}
+
+	if thresholds.PacketsPerSec <= 0 && thresholds.BytesPerSec <= 0 {
+		// Report any traffic
+		if data[NetPackets].(int64) == 0 {
+			return nil, nil
+		}
+		return core.NewWatchEvent(data), nil
+	}
+
+	if len(exceeded) == 0 {
+		return nil, nil
+	}
+	return core.NewWatchEvent(data), nil
 }
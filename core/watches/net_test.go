@@ -0,0 +1,237 @@
+package watches
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// netFixtureStart is when the first packet in the fixture was captured
+var netFixtureStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// writeNetFixture writes a pcap, or pcapng, file with 3 TCP packets of 154
+// bytes from 10.0.0.1 over 2 seconds, and a UDP packet of 92 bytes from
+// 10.0.0.3 between them
+func writeNetFixture(t *testing.T, ng bool) string {
+	t.Helper()
+	var path = filepath.Join(t.TempDir(), "fixture.pcap")
+	var file, err = os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var writer interface {
+		WritePacket(ci gopacket.CaptureInfo, data []byte) error
+	}
+	if ng {
+		var ngWriter *pcapgo.NgWriter
+		if ngWriter, err = pcapgo.NewNgWriter(file, layers.LinkTypeEthernet); err != nil {
+			t.Fatal(err)
+		}
+		defer ngWriter.Flush()
+		writer = ngWriter
+	} else {
+		var pcapWriter = pcapgo.NewWriter(file)
+		if err = pcapWriter.WriteFileHeader(65536, layers.LinkTypeEthernet); err != nil {
+			t.Fatal(err)
+		}
+		writer = pcapWriter
+	}
+
+	var write = func(offset time.Duration, src string, transport gopacket.SerializableLayer,
+		payload int) {
+		var ethernet = &layers.Ethernet{
+			SrcMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 1},
+			DstMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 2},
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+		var ip = &layers.IPv4{
+			Version: 4,
+			TTL:     64,
+			SrcIP:   net.ParseIP(src),
+			DstIP:   net.ParseIP("10.0.0.2"),
+		}
+		switch layer := transport.(type) {
+		case *layers.TCP:
+			ip.Protocol = layers.IPProtocolTCP
+			layer.SetNetworkLayerForChecksum(ip)
+		case *layers.UDP:
+			ip.Protocol = layers.IPProtocolUDP
+			layer.SetNetworkLayerForChecksum(ip)
+		}
+
+		var buffer = gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buffer,
+			gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+			ethernet, ip, transport, gopacket.Payload(make([]byte, payload))); err != nil {
+			t.Fatal(err)
+		}
+
+		var data = buffer.Bytes()
+		if err := writer.WritePacket(gopacket.CaptureInfo{
+			Timestamp:     netFixtureStart.Add(offset),
+			CaptureLength: len(data),
+			Length:        len(data),
+		}, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tcp = func() gopacket.SerializableLayer {
+		return &layers.TCP{SrcPort: 40000, DstPort: 80, ACK: true, Window: 1024}
+	}
+	write(0, "10.0.0.1", tcp(), 100)
+	write(time.Second, "10.0.0.1", tcp(), 100)
+	write(1500*time.Millisecond, "10.0.0.3",
+		&layers.UDP{SrcPort: 40001, DstPort: 5000}, 50)
+	write(2*time.Second, "10.0.0.1", tcp(), 100)
+
+	return path
+}
+
+// replayNet reads all the packets in a file, returning the event for them
+func replayNet(t *testing.T, file string, filter string,
+	thresholds NetThresholds) map[string]interface{} {
+	t.Helper()
+	var layer = "Ethernet"
+	var watch = NewNetFileWatch(&layer, &file, false, filter)
+	if watch == nil {
+		t.Fatal("failed to create watch")
+	}
+	watch.SetThresholds(thresholds)
+
+	// The first observation starts reading the file
+	if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+		t.Fatalf("expected nothing while starting, got %v %v", event, err)
+	}
+
+	var deadline = time.Now().Add(5 * time.Second)
+	for {
+		watch.stats.lock.Lock()
+		var complete = watch.stats.complete
+		watch.stats.lock.Unlock()
+		if complete {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the file to be read")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var event, err = watch.ObserveContext(context.Background())
+	if err != nil || event == nil {
+		t.Fatalf("expected the end of the file to be reported, got %v %v", event, err)
+	}
+
+	// Once the file has been reported the watch is done
+	var done, _ = watch.ObserveContext(context.Background())
+	if done == nil || !done.ShouldStop() {
+		t.Error("expected the watch to be complete")
+	}
+	return event.Data
+}
+
+func TestNetFileWatch(t *testing.T) {
+	var expected = map[string]interface{}{
+		NetPackets:       int64(4),
+		NetBytes:         int64(3*154 + 92),
+		NetPacketsPerSec: 2.0,
+		NetBytesPerSec:   float64(3*154+92) / 2,
+		NetInterval:      2 * time.Second,
+		NetComplete:      true,
+		NetProtocols: map[string]int64{
+			"Ethernet": 4,
+			"IPv4":     4,
+			"TCP":      3,
+			"UDP":      1,
+			"Payload":  4,
+		},
+		NetTopTalkers: []NetTalker{
+			{Endpoint: "10.0.0.1", Packets: 3, Bytes: 3 * 154},
+			{Endpoint: "10.0.0.3", Packets: 1, Bytes: 92},
+		},
+	}
+	for _, ng := range []bool{false, true} {
+		var data = replayNet(t, writeNetFixture(t, ng), "", NetThresholds{})
+		if !reflect.DeepEqual(data, expected) {
+			t.Errorf("pcapng=%v: expected %v, got %v", ng, expected, data)
+		}
+	}
+}
+
+func TestNetFileWatchThresholds(t *testing.T) {
+	var file = writeNetFixture(t, false)
+	var data = replayNet(t, file, "", NetThresholds{PacketsPerSec: 1,
+		BytesPerSec: 1000, TopTalkers: 1})
+
+	if exceeded, _ := data[NetExceeded].([]string); !reflect.DeepEqual(exceeded,
+		[]string{NetPacketsPerSec}) {
+		t.Errorf("expected packets per second to be exceeded, got %v", exceeded)
+	}
+
+	var expected = []NetTalker{{Endpoint: "10.0.0.1", Packets: 3, Bytes: 3 * 154}}
+	if talkers := data[NetTopTalkers]; !reflect.DeepEqual(talkers, expected) {
+		t.Errorf("expected the top talker %v, got %v", expected, talkers)
+	}
+
+	data = replayNet(t, file, "", NetThresholds{PacketsPerSec: 10})
+	if exceeded, ok := data[NetExceeded]; ok {
+		t.Errorf("expected no thresholds to be exceeded, got %v", exceeded)
+	}
+}
+
+func TestNetFileWatchFilter(t *testing.T) {
+	var data = replayNet(t, writeNetFixture(t, false), "udp", NetThresholds{})
+
+	if data[NetPackets] != int64(1) || data[NetBytes] != int64(92) {
+		t.Errorf("expected only the UDP packet, got %v packets of %v bytes",
+			data[NetPackets], data[NetBytes])
+	}
+	if protocols := data[NetProtocols].(map[string]int64); protocols["TCP"] != 0 {
+		t.Errorf("expected no TCP packets, got %v", protocols)
+	}
+	if _, ok := data[NetPacketsPerSec]; ok {
+		t.Error("expected no rates for a single packet")
+	}
+}
+
+func TestNetFileWatchErrors(t *testing.T) {
+	var layer, file = "Ethernet", writeNetFixture(t, false)
+	if NewNetFileWatch(&layer, &file, false, "not a filter (") != nil {
+		t.Error("expected an invalid filter to not be watched")
+	}
+
+	var missing = filepath.Join(t.TempDir(), "missing.pcap")
+	if NewNetFileWatch(&layer, &missing, false, "") != nil {
+		t.Error("expected a missing file to not be watched")
+	}
+
+	var notPcap = filepath.Join(t.TempDir(), "not.pcap")
+	writeFile(t, notPcap, "not a capture file")
+	if NewNetFileWatch(&layer, &notPcap, false, "") != nil {
+		t.Error("expected a file that is not a capture to not be watched")
+	}
+
+	var unknown = "NotALayer"
+	if NewNetFileWatch(&unknown, &file, false, "") != nil {
+		t.Error("expected an unknown layer to not be watched")
+	}
+}
+
+func TestDefaultNetCaptureOptions(t *testing.T) {
+	var expected = NetCaptureOptions{SnapLen: 65536, Promiscuous: true,
+		Timeout: time.Second}
+	if options := DefaultNetCaptureOptions(); options != expected {
+		t.Errorf("expected %+v, got %+v", expected, options)
+	}
+}
@@ -0,0 +1,251 @@
+package watches
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// DefaultSysRoot is where the sys filesystem is usually mounted
+const DefaultSysRoot = "/sys"
+
+// ErrInterfaceNotFound is returned when a watched network interface does not
+// exist
+var ErrInterfaceNotFound = errors.New("interface not found")
+
+// NetDevName is a key in WatchEvent for the name of a network interface
+var NetDevName = "netdev.name"
+
+// NetDevOperState is a key in WatchEvent for the operational state of a
+// network interface
+var NetDevOperState = "netdev.operstate"
+
+// NetDevPreviousOperState is a key in WatchEvent for the operational state of
+// a network interface when it was last observed
+var NetDevPreviousOperState = "netdev.previous_operstate"
+
+// NetDevExceeded is a key in WatchEvent for the thresholds that were exceeded
+var NetDevExceeded = "netdev.exceeded"
+
+// The keys in WatchEvent for the network interface counters
+var (
+	NetDevRxBytes   = "netdev.rx_bytes"
+	NetDevRxPackets = "netdev.rx_packets"
+	NetDevRxErrors  = "netdev.rx_errs"
+	NetDevRxDrops   = "netdev.rx_drop"
+	NetDevTxBytes   = "netdev.tx_bytes"
+	NetDevTxPackets = "netdev.tx_packets"
+	NetDevTxErrors  = "netdev.tx_errs"
+	NetDevTxDrops   = "netdev.tx_drop"
+)
+
+// The keys in WatchEvent for the network interface rates
+var (
+	NetDevRxBytesPerSec   = "netdev.rx_bytes_per_sec"
+	NetDevRxPacketsPerSec = "netdev.rx_packets_per_sec"
+	NetDevTxBytesPerSec   = "netdev.tx_bytes_per_sec"
+	NetDevTxPacketsPerSec = "netdev.tx_packets_per_sec"
+	NetDevErrorsPerSec    = "netdev.errs_per_sec"
+	NetDevDropsPerSec     = "netdev.drop_per_sec"
+)
+
+// netDevColumns are the counters in each line of /proc/net/dev, in order. The
+// receive and transmit fifo, frame, colls, carrier, compressed and multicast
+// counters are not used
+var netDevColumns = map[int]string{
+	0:  NetDevRxBytes,
+	1:  NetDevRxPackets,
+	2:  NetDevRxErrors,
+	3:  NetDevRxDrops,
+	8:  NetDevTxBytes,
+	9:  NetDevTxPackets,
+	10: NetDevTxErrors,
+	11: NetDevTxDrops,
+}
+
+// NetInterfaceThresholds are the rates above which a NetInterfaceWatch
+// reports an interface. Thresholds that are 0 are not checked
+type NetInterfaceThresholds struct {
+	RxBytesPerSec float64
+	TxBytesPerSec float64
+	ErrorsPerSec  float64
+	DropsPerSec   float64
+}
+
+// NetInterfaceWatch is a Watch that observes the traffic counters and link
+// state of a network interface
+type NetInterfaceWatch struct {
+	fs              procfs.FS
+	sysRoot         string
+	iface           string
+	thresholds      NetInterfaceThresholds
+	counters        map[string]int64
+	operState       string
+	lastObservation time.Time
+}
+
+// NewNetInterfaceWatch creates a new NetInterfaceWatch for the provided
+// interface, reporting when the rates exceed the thresholds or the link state
+// changes. The counters are read from the proc filesystem and the link state
+// from the sys filesystem mounted at sysRoot
+func NewNetInterfaceWatch(fs procfs.FS, sysRoot string, iface string,
+	thresholds NetInterfaceThresholds) *NetInterfaceWatch {
+	watch := new(NetInterfaceWatch)
+	watch.fs = fs
+	watch.sysRoot = sysRoot
+	watch.iface = iface
+	watch.thresholds = thresholds
+
+	// Init the watch with an initial value
+	watch.Observe()
+
+	return watch
+}
+
+// Observe whether a network interface is busy or has changed state
+func (watch *NetInterfaceWatch) Observe() *core.WatchEvent {
+	return observe(watch.iface, watch)
+}
+
+// ObserveContext observes the counters of the network interface, returning an
+// event if the rates since the last observation exceed the thresholds or the
+// link state has changed
+func (watch *NetInterfaceWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var counters, err = readNetDevCounters(watch.fs, watch.iface)
+	if err != nil {
+		return nil, core.NewWatchError(watch.iface, err)
+	}
+
+	var operState, stateErr = readOperState(watch.sysRoot, watch.iface)
+	if stateErr != nil {
+		log.Debug("No operstate for %s : %s", watch.iface, stateErr)
+		operState = "unknown"
+	}
+
+	var now = time.Now()
+	var since = now.Sub(watch.lastObservation).Seconds()
+	var lastCounters = watch.counters
+	var lastOperState = watch.operState
+
+	watch.counters = counters
+	watch.operState = operState
+	watch.lastObservation = now
+
+	if lastCounters == nil || since <= 0 {
+		// Nothing to compare with yet
+		return nil, nil
+	}
+
+	var data = map[string]interface{}{
+		NetDevName:      watch.iface,
+		NetDevOperState: operState,
+	}
+	for key, value := range counters {
+		data[key] = value
+	}
+
+	var rate = func(keys ...string) float64 {
+		var delta int64
+		for _, key := range keys {
+			var change = counters[key] - lastCounters[key]
+			if change < 0 {
+				// The counter has been reset
+				change = counters[key]
+			}
+			delta += change
+		}
+		return float64(delta) / since
+	}
+
+	var rates = map[string]float64{
+		NetDevRxBytesPerSec:   rate(NetDevRxBytes),
+		NetDevRxPacketsPerSec: rate(NetDevRxPackets),
+		NetDevTxBytesPerSec:   rate(NetDevTxBytes),
+		NetDevTxPacketsPerSec: rate(NetDevTxPackets),
+		NetDevErrorsPerSec:    rate(NetDevRxErrors, NetDevTxErrors),
+		NetDevDropsPerSec:     rate(NetDevRxDrops, NetDevTxDrops),
+	}
+	for key, value := range rates {
+		data[key] = value
+	}
+
+	log.Debug("iface=%s rx/s=%f tx/s=%f state=%s", watch.iface,
+		rates[NetDevRxBytesPerSec], rates[NetDevTxBytesPerSec], operState)
+
+	var exceeded = exceededThresholds(map[string][2]float64{
+		NetDevRxBytesPerSec: {rates[NetDevRxBytesPerSec],
+			watch.thresholds.RxBytesPerSec},
+		NetDevTxBytesPerSec: {rates[NetDevTxBytesPerSec],
+			watch.thresholds.TxBytesPerSec},
+		NetDevErrorsPerSec: {rates[NetDevErrorsPerSec],
+			watch.thresholds.ErrorsPerSec},
+		NetDevDropsPerSec: {rates[NetDevDropsPerSec],
+			watch.thresholds.DropsPerSec},
+	})
+
+	var stateChanged = operState != lastOperState
+	if len(exceeded) == 0 && !stateChanged {
+		// Nothing to report
+		return nil, nil
+	}
+
+	if stateChanged {
+		data[NetDevPreviousOperState] = lastOperState
+	}
+	if len(exceeded) > 0 {
+		data[NetDevExceeded] = exceeded
+	}
+
+	return core.NewWatchEvent(data), nil
+}
+
+// readNetDevCounters reads the counters for an interface from net/dev in the
+// proc filesystem
+func readNetDevCounters(fs procfs.FS, iface string) (map[string]int64, error) {
+	var devStr, err = fs.NetTable("dev")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(devStr, "\n") {
+		var colon = strings.Index(line, ":")
+		if colon < 0 || strings.TrimSpace(line[:colon]) != iface {
+			continue
+		}
+
+		var fields = strings.Fields(line[colon+1:])
+		var counters = make(map[string]int64)
+		for column, key := range netDevColumns {
+			if column >= len(fields) {
+				continue
+			}
+			var value, parseErr = strconv.ParseInt(fields[column], 10, 64)
+			if parseErr != nil {
+				return nil, parseErr
+			}
+			counters[key] = value
+		}
+		return counters, nil
+	}
+
+	return nil, ErrInterfaceNotFound
+}
+
+// readOperState reads the operational state (up, down, etc) of an interface
+// from the sys filesystem
+func readOperState(sysRoot string, iface string) (string, error) {
+	var state, err = utils.GetFileAsString(filepath.Join(sysRoot, "class", "net",
+		iface, "operstate"))
+	return strings.TrimSpace(state), err
+}
@@ -0,0 +1,148 @@
+package watches
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// netDevLine formats a line of net/dev for an interface with the provided rx
+// and tx bytes, packets, errors and drops
+func netDevLine(iface string, rx [4]int64, tx [4]int64) string {
+	return fmt.Sprintf("%6s: %d %d %d %d 0 0 0 0 %d %d %d %d 0 0 0 0\n", iface,
+		rx[0], rx[1], rx[2], rx[3], tx[0], tx[1], tx[2], tx[3])
+}
+
+func TestNetInterfaceWatch(t *testing.T) {
+	var header = "Inter-|   Receive                            |  Transmit\n" +
+		" face |bytes    packets errs drop fifo frame compressed multicast|" +
+		"bytes    packets errs drop fifo colls carrier compressed\n"
+	var fs = procfs.NewFS(writeFixture(t, map[string]string{
+		"net/dev": header + netDevLine("lo", [4]int64{5, 5, 0, 0}, [4]int64{5, 5, 0, 0}) +
+			netDevLine("eth0", [4]int64{1000, 10, 0, 0}, [4]int64{2000, 20, 0, 0}),
+	}))
+	var sysRoot = writeFixture(t, map[string]string{
+		"class/net/eth0/operstate": "up\n",
+	})
+
+	var update = func(rx [4]int64, tx [4]int64, state string) {
+		writeFile(t, fs.Path("net", "dev"), header+netDevLine("eth0", rx, tx))
+		writeFile(t, filepath.Join(sysRoot, "class", "net", "eth0", "operstate"),
+			state+"\n")
+	}
+
+	var watch = NewNetInterfaceWatch(fs, sysRoot, "eth0", NetInterfaceThresholds{
+		RxBytesPerSec: 100,
+		ErrorsPerSec:  1,
+	})
+	var expected = map[string]int64{
+		NetDevRxBytes: 1000, NetDevRxPackets: 10, NetDevRxErrors: 0, NetDevRxDrops: 0,
+		NetDevTxBytes: 2000, NetDevTxPackets: 20, NetDevTxErrors: 0, NetDevTxDrops: 0,
+	}
+	if !reflect.DeepEqual(watch.counters, expected) || watch.operState != "up" {
+		t.Fatalf("expected counters %v and up, got %v and %s", expected,
+			watch.counters, watch.operState)
+	}
+
+	// Observations are timed 10 seconds apart
+	var observe = func() map[string]interface{} {
+		t.Helper()
+		watch.lastObservation = time.Now().Add(-10 * time.Second)
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event == nil {
+			return nil
+		}
+		return event.Data
+	}
+	var checkRates = func(data map[string]interface{}, rates map[string]float64) {
+		t.Helper()
+		for key, rate := range rates {
+			if value, _ := data[key].(float64); math.Abs(value-rate) > rate/100+0.01 {
+				t.Errorf("expected %s of %f, got %v", key, rate, data[key])
+			}
+		}
+	}
+
+	// Below the thresholds in the same state is not reported
+	update([4]int64{1500, 15, 0, 0}, [4]int64{12000, 30, 0, 0}, "up")
+	if data := observe(); data != nil {
+		t.Fatalf("expected no event below the thresholds, got %v", data)
+	}
+
+	update([4]int64{4500, 25, 10, 5}, [4]int64{12000, 30, 10, 5}, "up")
+	var data = observe()
+	if data == nil {
+		t.Fatal("expected the thresholds to be exceeded")
+	}
+	checkRates(data, map[string]float64{
+		NetDevRxBytesPerSec:   300,
+		NetDevRxPacketsPerSec: 1,
+		NetDevTxBytesPerSec:   0,
+		NetDevErrorsPerSec:    2,
+		NetDevDropsPerSec:     1,
+	})
+	if exceeded := data[NetDevExceeded]; !reflect.DeepEqual(exceeded,
+		[]string{NetDevErrorsPerSec, NetDevRxBytesPerSec}) {
+		t.Errorf("expected errors and rx bytes to be exceeded, got %v", exceeded)
+	}
+	if data[NetDevName] != "eth0" || data[NetDevRxBytes] != int64(4500) {
+		t.Errorf("expected the eth0 counters, got %v", data)
+	}
+	if _, ok := data[NetDevPreviousOperState]; ok {
+		t.Error("expected no previous state when the state has not changed")
+	}
+
+	// A counter that wraps or is reset counts from 0
+	update([4]int64{2000, 30, 10, 5}, [4]int64{12000, 30, 10, 5}, "up")
+	checkRates(observe(), map[string]float64{
+		NetDevRxBytesPerSec:   200,
+		NetDevRxPacketsPerSec: 0.5,
+	})
+
+	// A change of state is always reported
+	update([4]int64{2000, 30, 10, 5}, [4]int64{12000, 30, 10, 5}, "down")
+	data = observe()
+	if data == nil {
+		t.Fatal("expected the change of state to be reported")
+	}
+	if data[NetDevOperState] != "down" || data[NetDevPreviousOperState] != "up" {
+		t.Errorf("expected up to down, got %v to %v",
+			data[NetDevPreviousOperState], data[NetDevOperState])
+	}
+	if _, ok := data[NetDevExceeded]; ok {
+		t.Errorf("expected no thresholds to be exceeded, got %v", data[NetDevExceeded])
+	}
+}
+
+func TestNetInterfaceWatchErrors(t *testing.T) {
+	var fs = procfs.NewFS(writeFixture(t, map[string]string{
+		"net/dev": "Inter-|\n face |\n" +
+			netDevLine("eth0", [4]int64{1, 1, 0, 0}, [4]int64{1, 1, 0, 0}),
+	}))
+
+	// An interface without an operstate is in an unknown state
+	var watch = NewNetInterfaceWatch(fs, t.TempDir(), "eth0", NetInterfaceThresholds{})
+	if watch.operState != "unknown" {
+		t.Errorf("expected an unknown state, got %s", watch.operState)
+	}
+
+	watch = NewNetInterfaceWatch(fs, t.TempDir(), "eth1", NetInterfaceThresholds{})
+	if _, err := watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error for a missing interface")
+	}
+
+	writeFile(t, fs.Path("net", "dev"), "Inter-|\n face |\n  eth0: 1 x 0 0\n")
+	watch = NewNetInterfaceWatch(fs, t.TempDir(), "eth0", NetInterfaceThresholds{})
+	if _, err := watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error for an invalid counter")
+	}
+}
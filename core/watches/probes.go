@@ -0,0 +1,302 @@
+package watches
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// ProbeAddress is a key in WatchEvent for the address being probed
+var ProbeAddress = "probe.address"
+
+// ProbeUp is a key in WatchEvent for whether a probe succeeded
+var ProbeUp = "probe.up"
+
+// ProbeLatency is a key in WatchEvent for how long a probe took
+var ProbeLatency = "probe.latency"
+
+// ProbeError is a key in WatchEvent for why a probe failed
+var ProbeError = "probe.error"
+
+// defaultProbeTimeout is used when a probe has no timeout
+var defaultProbeTimeout = 5 * time.Second
+
+// TCPConnectWatch is a Watch that probes whether a TCP port accepts
+// connections. It produces an event when the probe starts failing and when it
+// starts succeeding again
+type TCPConnectWatch struct {
+	address string
+	timeout time.Duration
+	up      bool
+}
+
+// NewTCPConnectWatch creates a new TCPConnectWatch for a host:port address
+func NewTCPConnectWatch(address string, timeout time.Duration) *TCPConnectWatch {
+	watch := new(TCPConnectWatch)
+	watch.address = address
+	watch.timeout = timeout
+	watch.up = true
+
+	if watch.timeout <= 0 {
+		watch.timeout = defaultProbeTimeout
+	}
+
+	return watch
+}
+
+// Observe whether the port has started or stopped accepting connections
+func (watch *TCPConnectWatch) Observe() *core.WatchEvent {
+	return observe(watch.address, watch)
+}
+
+// ObserveContext connects to the port, returning an event if it has started or
+// stopped accepting connections
+func (watch *TCPConnectWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	var dialer = net.Dialer{Timeout: watch.timeout}
+
+	var start = time.Now()
+	var conn, err = dialer.DialContext(ctx, "tcp", watch.address)
+	var latency = time.Since(start)
+
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if conn != nil {
+		conn.Close()
+	}
+
+	var up = err == nil
+	if up == watch.up {
+		return nil, nil
+	}
+	watch.up = up
+
+	var data = map[string]interface{}{
+		ProbeAddress: watch.address,
+		ProbeUp:      up,
+		ProbeLatency: latency,
+	}
+	if err != nil {
+		data[ProbeError] = err.Error()
+	}
+	return core.NewWatchEvent(data), nil
+}
+
+// DNSName is a key in WatchEvent for the name being resolved
+var DNSName = "dns.name"
+
+// DNSType is a key in WatchEvent for the type of record being resolved
+var DNSType = "dns.type"
+
+// DNSAnswers is a key in WatchEvent for the records a name resolved to
+var DNSAnswers = "dns.answers"
+
+// DNSPreviousAnswers is a key in WatchEvent for the records a name resolved to
+// when it was last observed
+var DNSPreviousAnswers = "dns.previousanswers"
+
+// DNSMissing is a key in WatchEvent for the expected records a name did not
+// resolve to
+var DNSMissing = "dns.missing"
+
+// DNSError is a key in WatchEvent for why a name could not be resolved
+var DNSError = "dns.error"
+
+// DNSStatus is a key in WatchEvent for the statuses of a DNS probe
+var DNSStatus = "dns.status"
+
+// DNSFailed is in DNSStatus when a name could not be resolved
+var DNSFailed = "failed"
+
+// DNSUnexpected is in DNSStatus when expected records were not resolved
+var DNSUnexpected = "unexpected"
+
+// DNSChanged is in DNSStatus when the records resolved have changed
+var DNSChanged = "changed"
+
+// DNSRecovered is in DNSStatus when a name resolves as expected again after
+// failing or resolving to unexpected records
+var DNSRecovered = "recovered"
+
+// DNSWatch is a Watch that resolves a name and observes the records it
+// resolves to
+type DNSWatch struct {
+	name     string
+	rrType   string
+	expected []string
+	resolver *net.Resolver
+	timeout  time.Duration
+	answers  []string
+	problem  string
+}
+
+// NewDNSWatch creates a new DNSWatch that resolves a name to records of the
+// provided type (A, AAAA, CNAME, MX, NS or TXT). If server is not empty the
+// name is resolved using the DNS server at that host:port rather than the
+// system resolver. Events are produced when the name stops resolving or an
+// expected record goes missing, when it recovers, and when the records change
+func NewDNSWatch(name string, rrType string, server string,
+	expected []string) *DNSWatch {
+	watch := new(DNSWatch)
+	watch.name = name
+	watch.rrType = strings.ToUpper(rrType)
+	watch.expected = expected
+	watch.timeout = defaultProbeTimeout
+	watch.resolver = net.DefaultResolver
+
+	if len(server) > 0 {
+		watch.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network string,
+				address string) (net.Conn, error) {
+				var dialer = net.Dialer{Timeout: watch.timeout}
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	switch watch.rrType {
+	case "A", "AAAA", "CNAME", "MX", "NS", "TXT":
+	default:
+		log.Error("Unsupported DNS record type %s", rrType)
+		return nil
+	}
+
+	return watch
+}
+
+// Observe whether the name resolves as expected
+func (watch *DNSWatch) Observe() *core.WatchEvent {
+	return observe(watch.name, watch)
+}
+
+// ObserveContext resolves the name, returning an event if it has stopped
+// resolving, expected records have gone missing, it has recovered from either
+// of those or the records have changed
+func (watch *DNSWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, watch.timeout)
+	defer cancel()
+
+	var data = map[string]interface{}{
+		DNSName: watch.name,
+		DNSType: watch.rrType,
+	}
+
+	var answers, err = watch.lookup(ctx)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, err
+		}
+		if watch.problem == DNSFailed {
+			return nil, nil
+		}
+		watch.problem = DNSFailed
+		data[DNSError] = err.Error()
+		data[DNSStatus] = []string{DNSFailed}
+		return core.NewWatchEvent(data), nil
+	}
+	sort.Strings(answers)
+
+	var status []string
+	var missing []string
+	for _, expected := range watch.expected {
+		if !containsString(answers, expected) {
+			missing = append(missing, expected)
+		}
+	}
+
+	// Problems are only reported when they start and when they end
+	var problem = ""
+	if len(missing) > 0 {
+		problem = DNSUnexpected
+		data[DNSMissing] = missing
+	}
+	if problem != watch.problem {
+		if len(problem) > 0 {
+			status = append(status, problem)
+		} else {
+			status = append(status, DNSRecovered)
+		}
+	}
+	watch.problem = problem
+
+	var previous = watch.answers
+	if previous != nil && strings.Join(previous, " ") != strings.Join(answers, " ") {
+		status = append(status, DNSChanged)
+		data[DNSPreviousAnswers] = previous
+	}
+	watch.answers = answers
+
+	if len(status) == 0 {
+		return nil, nil
+	}
+
+	data[DNSAnswers] = answers
+	data[DNSStatus] = status
+	return core.NewWatchEvent(data), nil
+}
+
+// lookup the records for the name
+func (watch *DNSWatch) lookup(ctx context.Context) ([]string, error) {
+	var answers []string
+
+	switch watch.rrType {
+	case "A", "AAAA":
+		var network = "ip4"
+		if watch.rrType == "AAAA" {
+			network = "ip6"
+		}
+		var ips, err = watch.resolver.LookupIP(ctx, network, watch.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		var cname, err = watch.resolver.LookupCNAME(ctx, watch.name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		var mxs, err = watch.resolver.LookupMX(ctx, watch.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case "NS":
+		var nss, err = watch.resolver.LookupNS(ctx, watch.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			answers = append(answers, ns.Host)
+		}
+	case "TXT":
+		var txts, err = watch.resolver.LookupTXT(ctx, watch.name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, txts...)
+	}
+
+	return answers, nil
+}
+
+// containsString returns true if the value is in the list
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
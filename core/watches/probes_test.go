@@ -0,0 +1,195 @@
+package watches
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+func TestTCPConnectWatch(t *testing.T) {
+	var listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var address = listener.Addr().String()
+	var watch = NewTCPConnectWatch(address, time.Second)
+
+	// Nothing is reported while the port stays up
+	if event, _ := watch.ObserveContext(context.Background()); event != nil {
+		t.Fatalf("expected no event while the port is up, got %v", event.Data)
+	}
+
+	listener.Close()
+	var event, _ = watch.ObserveContext(context.Background())
+	if event == nil || event.Get(ProbeUp) != false {
+		t.Fatal("expected an event when the port stops accepting connections")
+	}
+	if len(event.GetAsString(ProbeError)) == 0 {
+		t.Error("expected the reason the probe failed")
+	}
+	if event, _ = watch.ObserveContext(context.Background()); event != nil {
+		t.Errorf("expected no event while the port stays down, got %v", event.Data)
+	}
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skipf("cannot listen on %s again: %s", address, err)
+	}
+	defer listener.Close()
+
+	event, _ = watch.ObserveContext(context.Background())
+	if event == nil || event.Get(ProbeUp) != true {
+		t.Fatal("expected an event when the port accepts connections again")
+	}
+}
+
+// dnsStub is a DNS server that answers A queries with its current answers, or
+// fails them if it has none
+type dnsStub struct {
+	conn    net.PacketConn
+	lock    sync.Mutex
+	answers []net.IP
+}
+
+func newDNSStub(t *testing.T) *dnsStub {
+	var conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := new(dnsStub)
+	stub.conn = conn
+	t.Cleanup(func() { conn.Close() })
+
+	go stub.serve()
+	return stub
+}
+
+func (stub *dnsStub) set(answers ...string) {
+	stub.lock.Lock()
+	defer stub.lock.Unlock()
+
+	stub.answers = nil
+	for _, answer := range answers {
+		stub.answers = append(stub.answers, net.ParseIP(answer).To4())
+	}
+}
+
+func (stub *dnsStub) serve() {
+	var buffer = make([]byte, 512)
+	for {
+		var n, from, err = stub.conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		if reply := stub.reply(buffer[:n]); reply != nil {
+			stub.conn.WriteTo(reply, from)
+		}
+	}
+}
+
+// reply builds the response to a query, copying its question
+func (stub *dnsStub) reply(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// The question is the name labels, then the type and class
+	var end = 12
+	for end < len(query) && query[end] != 0 {
+		end += int(query[end]) + 1
+	}
+	end += 5
+	if end > len(query) {
+		return nil
+	}
+	var qtype = binary.BigEndian.Uint16(query[end-4:])
+
+	stub.lock.Lock()
+	var answers = stub.answers
+	stub.lock.Unlock()
+
+	var reply = make([]byte, 12, 512)
+	copy(reply, query[:2])
+	if len(answers) == 0 {
+		// SERVFAIL
+		binary.BigEndian.PutUint16(reply[2:], 0x8182)
+	} else {
+		binary.BigEndian.PutUint16(reply[2:], 0x8180)
+	}
+	binary.BigEndian.PutUint16(reply[4:], 1)
+	reply = append(reply, query[12:end]...)
+
+	if qtype != 1 {
+		return reply
+	}
+
+	binary.BigEndian.PutUint16(reply[6:], uint16(len(answers)))
+	for _, answer := range answers {
+		var record = make([]byte, 12)
+		binary.BigEndian.PutUint16(record[0:], 0xc00c) // name of the question
+		binary.BigEndian.PutUint16(record[2:], 1)      // A
+		binary.BigEndian.PutUint16(record[4:], 1)      // IN
+		binary.BigEndian.PutUint32(record[6:], 60)     // TTL
+		binary.BigEndian.PutUint16(record[10:], 4)
+		reply = append(append(reply, record...), answer...)
+	}
+	return reply
+}
+
+func TestDNSWatchTransitions(t *testing.T) {
+	var stub = newDNSStub(t)
+	stub.set("192.0.2.1")
+
+	var watch = NewDNSWatch("probe.clockwork.test.", "A",
+		stub.conn.LocalAddr().String(), []string{"192.0.2.1"})
+	watch.timeout = time.Second
+
+	var observe = func(answers []string, expected ...string) *core.WatchEvent {
+		t.Helper()
+		stub.set(answers...)
+
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expected) == 0 {
+			if event != nil {
+				t.Fatalf("%v: expected no event, got %v", answers, event.Data)
+			}
+			return nil
+		}
+		if event == nil {
+			t.Fatalf("%v: expected %v, got no event", answers, expected)
+		}
+		if status := event.Get(DNSStatus); !reflect.DeepEqual(status, expected) {
+			t.Fatalf("%v: expected %v, got %v", answers, expected, status)
+		}
+		return event
+	}
+
+	observe([]string{"192.0.2.1"})
+
+	// Failures are reported once, then the recovery
+	var event = observe(nil, DNSFailed)
+	if len(event.GetAsString(DNSError)) == 0 {
+		t.Error("expected the reason the name could not be resolved")
+	}
+	observe(nil)
+	observe([]string{"192.0.2.1"}, DNSRecovered)
+	observe([]string{"192.0.2.1"})
+
+	// As are unexpected records, which are also a change
+	event = observe([]string{"192.0.2.2"}, DNSUnexpected, DNSChanged)
+	if missing := event.Get(DNSMissing); !reflect.DeepEqual(missing, []string{"192.0.2.1"}) {
+		t.Errorf("expected 192.0.2.1 to be missing, got %v", missing)
+	}
+	observe([]string{"192.0.2.2"})
+	observe([]string{"192.0.2.1", "192.0.2.2"}, DNSRecovered, DNSChanged)
+	observe([]string{"192.0.2.1", "192.0.2.2"})
+}
@@ -2,11 +2,15 @@ package watches
 
 import (
 	"bufio"
+	"context"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
 	"github.com/deanydean/clockwork/core/utils"
 )
 
@@ -14,84 +18,179 @@ var log = utils.GetLogger()
 
 // ProcessDeathWatch watch that checks if a process has died
 type ProcessDeathWatch struct {
-	pid int
+	proc procfs.Proc
 }
 
 // Observe whether a process has died, returns a WatchEvent if it has, or nil
 // if not
 func (watch *ProcessDeathWatch) Observe() *core.WatchEvent {
-	if !utils.ProcessExists(watch.pid) {
-		return core.NewWatchEvent(nil)
+	return observe("process death", watch)
+}
+
+// ObserveContext observes whether a process has died
+func (watch *ProcessDeathWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !watch.proc.Exists() {
+		return core.NewWatchEvent(nil), nil
 	}
 
-	return nil
+	return nil, nil
 }
 
 // NewProcessDeathWatch returns a new ProcessDeathWatch for the provided pid
 func NewProcessDeathWatch(pid int) *ProcessDeathWatch {
+	return NewProcessDeathWatchFrom(procfs.Default, pid)
+}
+
+// NewProcessDeathWatchFrom returns a new ProcessDeathWatch for the provided
+// pid in the provided proc filesystem
+func NewProcessDeathWatchFrom(fs procfs.FS, pid int) *ProcessDeathWatch {
 	watch := new(ProcessDeathWatch)
-	watch.pid = pid
+	watch.proc = fs.Proc(pid)
 	return watch
 }
 
-// ProcessHighCPUWatch will watch for a process CPU going over a threshold
+// ProcessCPUOptions are the options for how a ProcessHighCPUWatch works out
+// the CPU usage of a process
+type ProcessCPUOptions struct {
+	// IncludeChildren adds the CPU time of reaped children to the process
+	IncludeChildren bool
+	// Normalized compares the threshold with the usage divided by the number
+	// of cores, instead of the usage of one core
+	Normalized bool
+}
+
+// ProcessHighCPUWatch will watch for a process CPU going over a threshold.
+// The CPU usage is worked out over the interval since it was last observed
 type ProcessHighCPUWatch struct {
-	cpuThreshold       float64
-	procStartTime      int
-	sysClockTick       int
-	procTimeSinceStart int
-	statsWatch         *ProcessStatsWatch
+	fs              procfs.FS
+	cpuThreshold    float64
+	options         ProcessCPUOptions
+	sysClockTick    int
+	cpuTicks        int64
+	startTime       uint64
+	lastObservation time.Time
+	statsWatch      *ProcessStatsWatch
 }
 
 // Observe whether a process CPU is high, returns a WatchEvent if it is, or nil
 // if it's not
 func (watch *ProcessHighCPUWatch) Observe() *core.WatchEvent {
-	var statsEvent = watch.statsWatch.Observe()
+	return observe("process cpu", watch)
+}
+
+// ObserveContext observes whether a process CPU is high, returning an error if
+// the process stats could not be read
+func (watch *ProcessHighCPUWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	var statsEvent, err = watch.statsWatch.ObserveContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get all the params we need to work out CPU usage
-	var uptime = utils.GetSystemUptime()
-	var utime = statsEvent.GetAsInteger(StatsProcTime)
-	var stime = statsEvent.GetAsInteger(StatsKernTime)
-	var cutime = statsEvent.GetAsInteger(StatsProcWaitTime)
-	var cstime = statsEvent.GetAsInteger(StatsKernWaitTime)
+	var stat = statsEvent.Get(StatsStat).(procfs.ProcStat)
+	var cpuTicks = int64(stat.UTime + stat.STime)
+	if watch.options.IncludeChildren {
+		cpuTicks += stat.CUTime + stat.CSTime
+	}
+
+	var now = time.Now()
+	var seconds = now.Sub(watch.lastObservation).Seconds()
+	var lastTicks = watch.cpuTicks
+	var firstObservation = watch.lastObservation.IsZero()
 
-	var totalTime = utime + stime + cutime + cstime
-	var seconds = uptime - float64(watch.procTimeSinceStart)
+	// If the ticks have gone down, or the process started at another time,
+	// then the pid has been reused by a new process
+	var newProcess = cpuTicks < lastTicks || stat.StartTime != watch.startTime
 
-	var cpuUsage = ((float64(totalTime) / float64(watch.sysClockTick)) /
-		seconds)
+	watch.cpuTicks = cpuTicks
+	watch.startTime = stat.StartTime
+	watch.lastObservation = now
 
+	if firstObservation || seconds <= 0 {
+		// Nothing to compare with yet
+		return nil, nil
+	}
+	if newProcess {
+		log.Debug("pid %d is a new process, starting its CPU usage again",
+			stat.PID)
+		return nil, nil
+	}
+
+	// Usage is a percentage of one core, so can go over 100 when the process
+	// uses more than one
+	var cpuUsage = float64(cpuTicks-lastTicks) / float64(watch.sysClockTick) /
+		seconds * 100
 	statsEvent.Data[StatsCPU] = cpuUsage
 
-	log.Debug("uptime=%d utime=%d stime=%d", uptime, utime, stime)
-	log.Debug("total=%d tick=%d seconds=%d usage=%d threshold=%d",
-		totalTime, watch.sysClockTick, seconds, cpuUsage, watch.cpuThreshold)
+	// The cores are counted from the proc filesystem rather than the cores
+	// this process is allowed to run on
+	var cores = 0
+	if hostStat, statErr := watch.fs.Stat(); statErr == nil {
+		cores = len(hostStat.CPUs)
+	} else if watch.options.Normalized {
+		return nil, core.NewWatchError("process cpu", statErr)
+	}
+
+	var usage = cpuUsage
+	if cores > 0 {
+		var normalizedUsage = cpuUsage / float64(cores)
+		statsEvent.Data[StatsCPUNormalized] = normalizedUsage
+		if watch.options.Normalized {
+			usage = normalizedUsage
+		}
+	}
 
-	if cpuUsage > watch.cpuThreshold {
-		return statsEvent
+	log.Debug("ticks=%d last=%d tick=%d seconds=%f usage=%f cores=%d threshold=%f",
+		cpuTicks, lastTicks, watch.sysClockTick, seconds, cpuUsage, cores,
+		watch.cpuThreshold)
+	if usage > watch.cpuThreshold {
+		return statsEvent, nil
 	}
 
-	return nil
+	return nil, nil
 }
 
 // NewProcessHighCPUWatch returns a new ProcessHighCPUWatch for the provided pid
-// with the provided high CPU threshold
+// with the provided high CPU threshold, as a percentage of one core
 func NewProcessHighCPUWatch(pid int, threshold float64) *ProcessHighCPUWatch {
+	return NewProcessHighCPUWatchFrom(procfs.Default, pid, threshold)
+}
+
+// NewProcessHighCPUWatchFrom returns a new ProcessHighCPUWatch for the
+// provided pid in the provided proc filesystem, as NewProcessHighCPUWatch does
+func NewProcessHighCPUWatchFrom(fs procfs.FS, pid int,
+	threshold float64) *ProcessHighCPUWatch {
+	return NewProcessHighCPUWatchWithOptions(fs, pid, threshold,
+		ProcessCPUOptions{})
+}
+
+// NewProcessHighCPUWatchWithOptions returns a new ProcessHighCPUWatch for the
+// provided pid in the proc filesystem with the provided high CPU threshold, as
+// a percentage of one core or of all the cores if the options are normalized
+func NewProcessHighCPUWatchWithOptions(fs procfs.FS, pid int, threshold float64,
+	options ProcessCPUOptions) *ProcessHighCPUWatch {
 	watch := new(ProcessHighCPUWatch)
+	watch.fs = fs
 	watch.cpuThreshold = threshold
+	watch.options = options
 	watch.statsWatch = new(ProcessStatsWatch)
-	watch.statsWatch.pid = pid
+	watch.statsWatch.proc = fs.Proc(pid)
 
-	var procStats = watch.statsWatch.Observe()
-	watch.procStartTime = procStats.GetAsInteger(StatsProcStartTime)
 	watch.sysClockTick = utils.GetSystemClockTick()
-
 	if watch.sysClockTick == -1 {
 		log.Warn("Failed to get system clock speed, cannot create watch")
 		return nil
 	}
 
-	watch.procTimeSinceStart = watch.procStartTime / watch.sysClockTick
+	// Init the watch with an initial value
+	if _, err := watch.ObserveContext(context.Background()); err != nil {
+		log.Warn("Failed to get stats for pid %d, cannot create watch", pid)
+		return nil
+	}
 
 	return watch
 }
@@ -105,91 +204,390 @@ type ProcessHighMemWatch struct {
 // Observe whether a process has high memory usage, returns a WatchEvent if it
 // has or nil if it hasn't
 func (watch *ProcessHighMemWatch) Observe() *core.WatchEvent {
-	var statsEvent = watch.statsWatch.Observe()
+	return observe("process memory", watch)
+}
 
-	var rss = statsEvent.GetAsInteger(StatsProcRSS)
+// ObserveContext observes whether a process has high memory usage, returning
+// an error if the process stats could not be read
+func (watch *ProcessHighMemWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	var statsEvent, err = watch.statsWatch.ObserveContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	var bytesInUse = rss * utils.GetPageSize()
+	var rss = statsEvent.Get(StatsStat).(procfs.ProcStat).RSS
+
+	var bytesInUse = rss * int64(utils.GetPageSize())
 	statsEvent.Data[StatsMem] = bytesInUse
 
 	if float64(bytesInUse) > watch.memThreshold {
-		return statsEvent
+		return statsEvent, nil
 	}
 
 	// Nothing to report
-	return nil
+	return nil, nil
 }
 
 // NewProcessHighMemWatch returns a new ProcessHighMemWatch for the provided pid
 // with the provided high memory threshold
 func NewProcessHighMemWatch(pid int, threshold float64) *ProcessHighMemWatch {
+	return NewProcessHighMemWatchFrom(procfs.Default, pid, threshold)
+}
+
+// NewProcessHighMemWatchFrom returns a new ProcessHighMemWatch for the
+// provided pid in the provided proc filesystem, as NewProcessHighMemWatch does
+func NewProcessHighMemWatchFrom(fs procfs.FS, pid int,
+	threshold float64) *ProcessHighMemWatch {
 	watch := new(ProcessHighMemWatch)
 	watch.memThreshold = threshold
 	watch.statsWatch = new(ProcessStatsWatch)
-	watch.statsWatch.pid = pid
+	watch.statsWatch.proc = fs.Proc(pid)
+	return watch
+}
+
+// FDCount is a key in WatchEvent for the number of open file descriptors of a
+// process
+var FDCount = "fd.count"
+
+// FDLimit is a key in WatchEvent for the soft limit of open file descriptors
+// of a process
+var FDLimit = "fd.limit"
+
+// FDRatio is a key in WatchEvent for the open file descriptors of a process as
+// a fraction of its limit
+var FDRatio = "fd.ratio"
+
+// ProcessFDWatch will watch for the open file descriptors of a process getting
+// close to its Max open files limit
+type ProcessFDWatch struct {
+	ratio float64
+	proc  procfs.Proc
+}
+
+// Observe whether a process has too many open file descriptors, returns a
+// WatchEvent if it has or nil if it hasn't
+func (watch *ProcessFDWatch) Observe() *core.WatchEvent {
+	return observe("process fds", watch)
+}
+
+// ObserveContext observes whether a process has too many open file
+// descriptors, returning an error if they could not be read
+func (watch *ProcessFDWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var count, err = watch.proc.FDCount()
+	var limits procfs.ProcLimits
+	if err == nil {
+		limits, err = watch.proc.Limits()
+	}
+
+	if err != nil {
+		if !watch.proc.Exists() {
+			err = ErrProcessNotFound
+		}
+		return nil, core.NewWatchError("process fds", err)
+	}
+
+	var limit = limits[procfs.LimitOpenFiles].Soft
+	if limit == procfs.Unlimited || limit <= 0 {
+		// Nothing to compare with
+		return nil, nil
+	}
+
+	var ratio = float64(count) / float64(limit)
+	log.Debug("pid=%d fds=%d limit=%d ratio=%f", watch.proc.PID, count, limit,
+		ratio)
+
+	if ratio >= watch.ratio {
+		return core.NewWatchEvent(map[string]interface{}{
+			FDCount: count,
+			FDLimit: limit,
+			FDRatio: ratio,
+		}), nil
+	}
+
+	// Nothing to report
+	return nil, nil
+}
+
+// NewProcessFDWatch returns a new ProcessFDWatch for the provided pid in the
+// proc filesystem that reports when the open file descriptors reach the ratio
+// (such as 0.8) of the Max open files limit
+func NewProcessFDWatch(fs procfs.FS, pid int, ratio float64) *ProcessFDWatch {
+	watch := new(ProcessFDWatch)
+	watch.ratio = ratio
+	watch.proc = fs.Proc(pid)
+	return watch
+}
+
+// ThreadsCount is a key in WatchEvent for the number of threads of a process
+var ThreadsCount = "threads.count"
+
+// ProcessThreadsWatch will watch for the number of threads of a process going
+// over a threshold
+type ProcessThreadsWatch struct {
+	threshold int
+	proc      procfs.Proc
+}
+
+// Observe whether a process has too many threads, returns a WatchEvent if it
+// has or nil if it hasn't
+func (watch *ProcessThreadsWatch) Observe() *core.WatchEvent {
+	return observe("process threads", watch)
+}
+
+// ObserveContext observes whether a process has too many threads, returning
+// an error if the process status could not be read
+func (watch *ProcessThreadsWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var status, err = watch.proc.Status()
+	if err != nil {
+		if !watch.proc.Exists() {
+			err = ErrProcessNotFound
+		}
+		return nil, core.NewWatchError("process threads", err)
+	}
+
+	if status.Threads > watch.threshold {
+		return core.NewWatchEvent(map[string]interface{}{
+			ThreadsCount: status.Threads,
+		}), nil
+	}
+
+	// Nothing to report
+	return nil, nil
+}
+
+// NewProcessThreadsWatch returns a new ProcessThreadsWatch for the provided
+// pid in the proc filesystem with the provided thread count threshold
+func NewProcessThreadsWatch(fs procfs.FS, pid int,
+	threshold int) *ProcessThreadsWatch {
+	watch := new(ProcessThreadsWatch)
+	watch.threshold = threshold
+	watch.proc = fs.Proc(pid)
 	return watch
 }
 
+// StateName is a key in WatchEvent for the state of a process, such as Z for
+// zombie or D for uninterruptible sleep
+var StateName = "state.name"
+
+// StateSince is a key in WatchEvent for when a process was first seen in its
+// state
+var StateSince = "state.since"
+
+// StateDuration is a key in WatchEvent for how long a process has been in its
+// state
+var StateDuration = "state.duration"
+
+// The process states that a ProcessStuckWatch looks for
+var (
+	StateZombie          = "Z"
+	StateUninterruptible = "D"
+)
+
+// ProcessStuckWatch will watch for a process staying a zombie or in
+// uninterruptible sleep for too long
+type ProcessStuckWatch struct {
+	duration time.Duration
+	proc     procfs.Proc
+	state    string
+	since    time.Time
+}
+
+// Observe whether a process is stuck, returns a WatchEvent if it is or nil if
+// it isn't
+func (watch *ProcessStuckWatch) Observe() *core.WatchEvent {
+	return observe("process state", watch)
+}
+
+// ObserveContext observes whether a process is stuck, returning an error if
+// the process stats could not be read. The state is only seen when the watch
+// is observed, so short stays in a state can be missed and how long it has
+// been in a state is from when it was first seen
+func (watch *ProcessStuckWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var stat, err = watch.proc.Stat()
+	if err != nil {
+		if !watch.proc.Exists() {
+			err = ErrProcessNotFound
+		}
+		return nil, core.NewWatchError("process state", err)
+	}
+
+	var now = time.Now()
+	if stat.State != watch.state {
+		watch.state = stat.State
+		watch.since = now
+	}
+
+	if watch.state != StateZombie && watch.state != StateUninterruptible {
+		// Nothing to report
+		return nil, nil
+	}
+
+	var stuckFor = now.Sub(watch.since)
+	log.Debug("pid=%d state=%s for=%s", watch.proc.PID, watch.state, stuckFor)
+
+	if stuckFor >= watch.duration {
+		return core.NewWatchEvent(map[string]interface{}{
+			StateName:     watch.state,
+			StateSince:    watch.since,
+			StateDuration: stuckFor,
+		}), nil
+	}
+
+	// Nothing to report
+	return nil, nil
+}
+
+// NewProcessStuckWatch returns a new ProcessStuckWatch for the provided pid in
+// the proc filesystem that reports when it has been a zombie or in
+// uninterruptible sleep for the provided duration
+func NewProcessStuckWatch(fs procfs.FS, pid int,
+	duration time.Duration) *ProcessStuckWatch {
+	watch := new(ProcessStuckWatch)
+	watch.duration = duration
+	watch.proc = fs.Proc(pid)
+
+	// Init the watch with an initial value
+	watch.Observe()
+
+	return watch
+}
+
+// ProcessIOThresholds are the rates above which a ProcessHighIOWatch reports
+// a process. Thresholds that are 0 are not checked
+type ProcessIOThresholds struct {
+	ReadBytesPerSec  float64
+	WriteBytesPerSec float64
+	SyscallsPerSec   float64
+}
+
+// ProcessHighIOWatch will watch for the IO rates of a process going over
+// thresholds. The rates are worked out over the interval since it was last
+// observed
 type ProcessHighIOWatch struct {
-	ioThreshold     float64
+	thresholds      ProcessIOThresholds
 	lastObservation time.Time
-	bytesRead       int64
-	bytesWritten    int64
+	counters        procfs.ProcIO
 	ioWatch         *ProcessIOWatch
 }
 
+// Observe whether a process has high IO, returns a WatchEvent if it has, or
+// nil if it hasn't
 func (watch *ProcessHighIOWatch) Observe() *core.WatchEvent {
-	var ioEvent = watch.ioWatch.Observe()
-
-	// Work out how much IO the process has performed sine the last check
-	var read = ioEvent.GetAsInteger(IOReadBytes)
-	var written = ioEvent.GetAsInteger(IOWriteBytes)
+	return observe("process io", watch)
+}
 
-	// TODO Check errors
-	if read == -1 || written == -1 {
-		log.Warn("Failed to get io info")
-		return nil
+// ObserveContext observes the IO rates of a process, returning an error if the
+// process IO could not be read. The error wraps ErrProcessNotFound if the
+// process has gone
+func (watch *ProcessHighIOWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	var ioEvent, err = watch.ioWatch.ObserveContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	// Work out how much IO the process has performed since the last check
+	var counters = ioEvent.Get(IOStats).(procfs.ProcIO)
 	var now = time.Now()
-	var since = now.Sub(watch.lastObservation) / time.Second
+	var since = now.Sub(watch.lastObservation).Seconds()
+	var lastCounters = watch.counters
+	var firstObservation = watch.lastObservation.IsZero()
 
-	if since > 0 {
-		var writesPerSec = (int64(written) - watch.bytesWritten) / int64(since)
-		var readsPerSec = (int64(read) - watch.bytesRead) / int64(since)
+	watch.counters = counters
+	watch.lastObservation = now
 
-		log.Debug("rchar=%d sysrc=%d read=%d read/s=%d",
-			ioEvent.GetAsInteger(IOReadChar),
-			ioEvent.GetAsInteger(IOReadCalls),
-			read, readsPerSec)
-		log.Debug("wchar=%d syswc=%d written=%d read/s=%d",
-			ioEvent.GetAsInteger(IOWriteChar),
-			ioEvent.GetAsInteger(IOWriteCalls),
-			written, writesPerSec)
+	if firstObservation || since <= 0 {
+		// Nothing to compare with yet
+		return nil, nil
+	}
 
-		ioEvent.Data[IOReadsPerSec] = readsPerSec
-		ioEvent.Data[IOWritesPerSec] = writesPerSec
+	var rate = func(value uint64, last uint64) float64 {
+		if value < last {
+			// The process has been replaced by one with the same pid
+			return float64(value) / since
+		}
+		return float64(value-last) / since
 	}
 
-	watch.lastObservation = now
-	watch.bytesRead = int64(read)
-	watch.bytesWritten = int64(written)
+	var readsPerSec = rate(counters.ReadBytes, lastCounters.ReadBytes)
+	var writesPerSec = rate(counters.WriteBytes, lastCounters.WriteBytes)
+	var syscallsPerSec = rate(counters.SyscR, lastCounters.SyscR) +
+		rate(counters.SyscW, lastCounters.SyscW)
 
-	// Nothing to report
-	return nil
+	log.Debug("rchar=%d syscr=%d read=%d read/s=%f",
+		counters.RChar, counters.SyscR, counters.ReadBytes, readsPerSec)
+	log.Debug("wchar=%d syscw=%d written=%d write/s=%f",
+		counters.WChar, counters.SyscW, counters.WriteBytes, writesPerSec)
+
+	ioEvent.Data[IOReadsPerSec] = readsPerSec
+	ioEvent.Data[IOWritesPerSec] = writesPerSec
+	ioEvent.Data[IOSyscallsPerSec] = syscallsPerSec
+
+	var exceeded []string
+	if watch.thresholds.ReadBytesPerSec > 0 &&
+		readsPerSec > watch.thresholds.ReadBytesPerSec {
+		exceeded = append(exceeded, IOReadsPerSec)
+	}
+	if watch.thresholds.WriteBytesPerSec > 0 &&
+		writesPerSec > watch.thresholds.WriteBytesPerSec {
+		exceeded = append(exceeded, IOWritesPerSec)
+	}
+	if watch.thresholds.SyscallsPerSec > 0 &&
+		syscallsPerSec > watch.thresholds.SyscallsPerSec {
+		exceeded = append(exceeded, IOSyscallsPerSec)
+	}
+
+	if len(exceeded) == 0 {
+		// Nothing to report
+		return nil, nil
+	}
+
+	ioEvent.Data[IOExceeded] = exceeded
+	return ioEvent, nil
 }
 
+// NewProcessHighIOWatch returns a new ProcessHighIOWatch for the provided pid
+// that reports when the bytes read or written per second go over threshold
 func NewProcessHighIOWatch(pid int, threshold float64) *ProcessHighIOWatch {
+	return NewProcessHighIOWatchFrom(procfs.Default, pid, threshold)
+}
+
+// NewProcessHighIOWatchFrom returns a new ProcessHighIOWatch for the provided
+// pid in the provided proc filesystem, as NewProcessHighIOWatch does
+func NewProcessHighIOWatchFrom(fs procfs.FS, pid int,
+	threshold float64) *ProcessHighIOWatch {
+	return NewProcessHighIOWatchWithThresholds(fs, pid, ProcessIOThresholds{
+		ReadBytesPerSec:  threshold,
+		WriteBytesPerSec: threshold,
+	})
+}
+
+// NewProcessHighIOWatchWithThresholds returns a new ProcessHighIOWatch for the
+// provided pid in the proc filesystem that reports when its IO rates go over
+// the thresholds
+func NewProcessHighIOWatchWithThresholds(fs procfs.FS, pid int,
+	thresholds ProcessIOThresholds) *ProcessHighIOWatch {
 	watch := new(ProcessHighIOWatch)
-	watch.ioThreshold = threshold
+	watch.thresholds = thresholds
 	watch.ioWatch = new(ProcessIOWatch)
-	watch.ioWatch.pid = pid
+	watch.ioWatch.proc = fs.Proc(pid)
 
 	// Init the watch with an initial value
-	watch.bytesRead = 0
-	watch.bytesWritten = 0
-	watch.lastObservation = time.Now()
-	watch.Observe()
+	if _, err := watch.ObserveContext(context.Background()); err != nil {
+		log.Warn("Failed to get io for pid %d, cannot create watch", pid)
+		return nil
+	}
 
 	return watch
 }
@@ -197,9 +595,18 @@ func NewProcessHighIOWatch(pid int, threshold float64) *ProcessHighIOWatch {
 // StatsRaw is a key in WatchEvent for raw process information
 var StatsRaw = "stats.raw"
 
-// StatsCPU is a key in WatchEvent for process CPU usage
+// StatsStat is a key in WatchEvent for the parsed process stat file, as a
+// procfs.ProcStat
+var StatsStat = "stats.stat"
+
+// StatsCPU is a key in WatchEvent for process CPU usage, as a percentage of
+// one core
 var StatsCPU = "stats.cpu"
 
+// StatsCPUNormalized is a key in WatchEvent for process CPU usage divided by
+// the number of cores, left out if the cores cannot be counted
+var StatsCPUNormalized = "stats.cpu_normalized"
+
 // StatsMem is a key in WatchEvent for process memory usage
 var StatsMem = "stats.mem"
 
@@ -226,39 +633,53 @@ var StatsProcStartTime = "stats.procstarttime"
 
 // ProcessStatsWatch watch that observes process information
 type ProcessStatsWatch struct {
-	pid int
+	proc procfs.Proc
 }
 
 // Observe process stats for the watch's pid
 func (watch *ProcessStatsWatch) Observe() *core.WatchEvent {
-	var statsStr, err = utils.GetProcessStats(watch.pid)
+	return observe("process stats", watch)
+}
+
+// ObserveContext observes process stats for the watch's pid, returning an
+// error if the stats could not be read
+func (watch *ProcessStatsWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var stat, err = watch.proc.Stat()
 
 	if err != nil {
-		return nil
+		if !watch.proc.Exists() {
+			err = ErrProcessNotFound
+		}
+		return nil, core.NewWatchError("process stats", err)
 	}
 
-	// Parse stats file and put in the defaults
+	// Put in the defaults
 	var stats = make(map[string]interface{})
-	var rawStats = strings.Split(statsStr, " ")
-	stats[StatsRaw] = rawStats
+	stats[StatsRaw] = stat.Fields
+	stats[StatsStat] = stat
 	stats[StatsTimestamp] = time.Now()
 
 	// Put in the specifics
-	stats[StatsProcTime] = rawStats[13]
-	stats[StatsKernTime] = rawStats[14]
-	stats[StatsProcWaitTime] = rawStats[15]
-	stats[StatsKernWaitTime] = rawStats[16]
-	stats[StatsProcStartTime] = rawStats[21]
-	stats[StatsProcRSS] = rawStats[23]
-
-	return core.NewWatchEvent(stats)
+	stats[StatsProcTime] = strconv.FormatUint(stat.UTime, 10)
+	stats[StatsKernTime] = strconv.FormatUint(stat.STime, 10)
+	stats[StatsProcWaitTime] = strconv.FormatInt(stat.CUTime, 10)
+	stats[StatsKernWaitTime] = strconv.FormatInt(stat.CSTime, 10)
+	stats[StatsProcStartTime] = strconv.FormatUint(stat.StartTime, 10)
+	stats[StatsProcRSS] = strconv.FormatInt(stat.RSS, 10)
+
+	return core.NewWatchEvent(stats), nil
 }
 
 type ProcessIOWatch struct {
-	pid int
+	proc procfs.Proc
 }
 
 var IORaw = "io.raw"
+var IOStats = "io.stats"
 var IOReadChar = "io.rchar"
 var IOWriteChar = "io.wchar"
 var IOReadCalls = "io.syscr"
@@ -268,27 +689,52 @@ var IOWriteBytes = "io.write_bytes"
 var IOCancelledWriteBytes = "io.cancelled_write_bytes"
 var IOWritesPerSec = "io.writes_per_sec"
 var IOReadsPerSec = "io.reads_per_sec"
+var IOSyscallsPerSec = "io.syscalls_per_sec"
+var IOExceeded = "io.exceeded"
 
 func (watch *ProcessIOWatch) Observe() *core.WatchEvent {
-	var ioStr, err = utils.GetProcessIO(watch.pid)
+	return observe("process io", watch)
+}
 
-	if err != nil {
-		return nil
+// ObserveContext observes the IO counters for the watch's pid, returning an
+// error if they could not be read
+func (watch *ProcessIOWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	var io = make(map[string]interface{})
-	var rawIO = strings.Split(ioStr, "\n")
-	io[IORaw] = rawIO
+	var counters, err = watch.proc.IO()
 
-	// Parse the lines, using the key for the event key
-	for _, line := range rawIO {
-		var kv = strings.Split(line, ":")
-		if len(kv) == 2 {
-			io["io."+kv[0]] = strings.TrimSpace(kv[1])
+	if err != nil {
+		if !watch.proc.Exists() {
+			err = ErrProcessNotFound
 		}
+		return nil, core.NewWatchError("process io", err)
 	}
 
-	return core.NewWatchEvent(io)
+	var io = map[string]interface{}{
+		IOStats: counters,
+	}
+	var values = map[string]uint64{
+		IOReadChar:            counters.RChar,
+		IOWriteChar:           counters.WChar,
+		IOReadCalls:           counters.SyscR,
+		IOWriteCalls:          counters.SyscW,
+		IOReadBytes:           counters.ReadBytes,
+		IOWriteBytes:          counters.WriteBytes,
+		IOCancelledWriteBytes: counters.CancelledWriteBytes,
+	}
+
+	var rawIO []string
+	for key, value := range values {
+		io[key] = strconv.FormatUint(value, 10)
+		rawIO = append(rawIO, strings.TrimPrefix(key, "io.")+": "+
+			strconv.FormatUint(value, 10))
+	}
+	sort.Strings(rawIO)
+	io[IORaw] = rawIO
+
+	return core.NewWatchEvent(io), nil
 }
 
 // CommandWatch runs and watches a command
@@ -302,6 +748,15 @@ type CommandWatch struct {
 
 // Observe a command
 func (watch *CommandWatch) Observe() *core.WatchEvent {
+	return observe("command", watch)
+}
+
+// ObserveContext observes the output of a command
+func (watch *CommandWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// The data to go into the event
 	data := make(map[string]interface{})
 
@@ -309,7 +764,7 @@ func (watch *CommandWatch) Observe() *core.WatchEvent {
 		log.Warn("Watch is complete, setting status on event to -1")
 		e := core.NewWatchEvent(data)
 		e.SetStatus(-1, true)
-		return e
+		return e, nil
 	}
 
 	// Read output
@@ -344,7 +799,7 @@ func (watch *CommandWatch) Observe() *core.WatchEvent {
 		}
 	}
 
-	return core.NewWatchEvent(data)
+	return core.NewWatchEvent(data), nil
 }
 
 // NewCommandWatch creates a CommandWatch
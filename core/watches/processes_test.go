@@ -0,0 +1,151 @@
+package watches
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// writeStat writes the stat file of a process with its CPU ticks and start
+// time
+func writeStat(t *testing.T, fs procfs.FS, pid int, ticks int, start int) {
+	var stat = fmt.Sprintf("%d (worker) S 1 %d %d 0 -1 0 0 0 0 0 %d 0 0 0 "+
+		"20 0 1 0 %d 1000000 100\n", pid, pid, pid, ticks, start)
+	if err := ioutil.WriteFile(fs.Path(fmt.Sprint(pid), "stat"), []byte(stat),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessDeathWatch(t *testing.T) {
+	var fs = procfs.NewFS(writeFixture(t, map[string]string{"10/comm": "worker\n"}))
+	var watch = NewProcessDeathWatchFrom(fs, 10)
+
+	if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+		t.Fatalf("expected no event while the process runs, got %v %v", event, err)
+	}
+
+	if err := os.RemoveAll(fs.Path("10")); err != nil {
+		t.Fatal(err)
+	}
+	if event, _ := watch.ObserveContext(context.Background()); event == nil {
+		t.Error("expected an event when the process has died")
+	}
+}
+
+func TestProcessHighCPUWatchPidReuse(t *testing.T) {
+	var fs = procfs.NewFS(writeFixture(t, map[string]string{"10/comm": "worker\n"}))
+	writeStat(t, fs, 10, 1000, 500)
+
+	var watch = NewProcessHighCPUWatchFrom(fs, 10, 0)
+	if watch == nil {
+		t.Fatal("expected a watch for the process")
+	}
+
+	var observe = func(ticks int, start int) bool {
+		t.Helper()
+		// Make sure some time has passed since the last observation
+		time.Sleep(10 * time.Millisecond)
+		writeStat(t, fs, 10, ticks, start)
+
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return event != nil
+	}
+
+	if !observe(1100, 500) {
+		t.Fatal("expected an event when the process uses CPU")
+	}
+
+	// A new process with the pid that has used fewer ticks, or that started
+	// at another time, has no usage until it is observed again
+	if observe(50, 900) {
+		t.Error("expected no event when the pid is reused with fewer ticks")
+	}
+	if !observe(150, 900) {
+		t.Error("expected an event when the new process uses CPU")
+	}
+	if observe(200, 1200) {
+		t.Error("expected no event when the pid is reused with more ticks")
+	}
+	if !observe(300, 1200) {
+		t.Error("expected an event when the new process uses CPU")
+	}
+
+	if err := os.RemoveAll(fs.Path("10")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error when the process has gone")
+	}
+}
+
+func TestProcessHighCPUWatchNormalized(t *testing.T) {
+	var cpus = "cpu  4 0 4 40 0 0 0 0 0 0\n"
+	for i := 0; i < 4; i++ {
+		cpus += fmt.Sprintf("cpu%d 1 0 1 10 0 0 0 0 0 0\n", i)
+	}
+	var fs = procfs.NewFS(writeFixture(t, map[string]string{
+		"10/comm": "worker\n",
+		"stat":    cpus + "btime 1700000000\n",
+	}))
+
+	// The process uses all of one core for a second, which is a quarter of
+	// the 4 cores
+	var observe = func(threshold float64) map[string]interface{} {
+		t.Helper()
+		writeStat(t, fs, 10, 1000, 500)
+		var watch = NewProcessHighCPUWatchWithOptions(fs, 10, threshold,
+			ProcessCPUOptions{Normalized: true})
+		if watch == nil {
+			t.Fatal("expected a watch for the process")
+		}
+
+		watch.lastObservation = watch.lastObservation.Add(-time.Second)
+		writeStat(t, fs, 10, 1000+watch.sysClockTick, 500)
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event == nil {
+			return nil
+		}
+		return event.Data
+	}
+
+	var data = observe(20)
+	if data == nil {
+		t.Fatal("expected the normalized usage to be over the threshold")
+	}
+	if usage := data[StatsCPUNormalized].(float64); usage < 24 || usage > 25.1 {
+		t.Errorf("expected a quarter of the cores to be used, got %f", usage)
+	}
+	if data = observe(30); data != nil {
+		t.Errorf("expected the normalized usage to be under the threshold, got %v",
+			data[StatsCPUNormalized])
+	}
+}
+
+func TestProcessWatchesUseDefaultProc(t *testing.T) {
+	if !procfs.Default.Proc(os.Getpid()).Exists() {
+		t.Skip("no proc filesystem")
+	}
+
+	var watch = NewProcessDeathWatch(os.Getpid())
+	if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+		t.Errorf("expected this process to be running, got %v %v", event, err)
+	}
+	if NewProcessHighCPUWatch(os.Getpid(), 100) == nil {
+		t.Error("expected a CPU watch for this process")
+	}
+	if NewProcessHighMemWatch(os.Getpid(), 0) == nil {
+		t.Error("expected a memory watch for this process")
+	}
+}
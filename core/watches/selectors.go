@@ -0,0 +1,285 @@
+package watches
+
+import (
+	"context"
+	"errors"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// ProcessPID is a key in WatchEvent for the pid of the process an event is for
+var ProcessPID = "process.pid"
+
+// ProcessSelector selects processes by their attributes. Only processes
+// matching all the fields that are set are selected
+type ProcessSelector struct {
+	// Comm is the command name of the process
+	Comm string
+	// Cmdline is a regex matched against the space separated command line
+	Cmdline string
+	// Exe is the path of the executable of the process
+	Exe string
+	// User is the name or uid of the user running the process
+	User string
+	// PidFile is a file containing the pid of the process
+	PidFile string
+	// Cgroup is a cgroup path that the process must be in or below
+	Cgroup string
+}
+
+// Pids gets the pids of the running processes that match the selector
+func (selector ProcessSelector) Pids() ([]int, error) {
+	var matcher, err = newProcessMatcher(procfs.Default, selector)
+	if err != nil {
+		return nil, err
+	}
+	return matcher.pids()
+}
+
+// processMatcher matches processes against a selector, with the fields that
+// need parsing parsed once
+type processMatcher struct {
+	selector     ProcessSelector
+	fs           procfs.FS
+	cmdlineRegex *regexp.Regexp
+	uid          int
+}
+
+// newProcessMatcher parses the selector, returning an error if the cmdline
+// regex is invalid or the user does not exist
+func newProcessMatcher(fs procfs.FS,
+	selector ProcessSelector) (*processMatcher, error) {
+	matcher := new(processMatcher)
+	matcher.selector = selector
+	matcher.fs = fs
+	matcher.uid = -1
+
+	if len(selector.Cmdline) > 0 {
+		var re, err = regexp.Compile(selector.Cmdline)
+		if err != nil {
+			return nil, err
+		}
+		matcher.cmdlineRegex = re
+	}
+
+	if len(selector.User) > 0 {
+		if uid, err := strconv.Atoi(selector.User); err == nil {
+			matcher.uid = uid
+		} else {
+			var found, lookupErr = user.Lookup(selector.User)
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			matcher.uid, _ = strconv.Atoi(found.Uid)
+		}
+	}
+
+	return matcher, nil
+}
+
+// pids gets the pids of the running processes that match
+func (matcher *processMatcher) pids() ([]int, error) {
+	var candidates []int
+	if len(matcher.selector.PidFile) > 0 {
+		var pidStr, err = utils.GetFileAsString(matcher.selector.PidFile)
+		if err != nil {
+			return nil, err
+		}
+		var pid, parseErr = strconv.Atoi(strings.TrimSpace(pidStr))
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		if matcher.fs.Proc(pid).Exists() {
+			candidates = []int{pid}
+		}
+	} else {
+		var all, err = matcher.fs.Pids()
+		if err != nil {
+			return nil, err
+		}
+		candidates = all
+	}
+
+	var pids []int
+	for _, pid := range candidates {
+		if matcher.matches(matcher.fs.Proc(pid)) {
+			pids = append(pids, pid)
+		}
+	}
+
+	sort.Ints(pids)
+	return pids, nil
+}
+
+// matches returns true if the process matches. Processes that cannot be read
+// do not match
+func (matcher *processMatcher) matches(proc procfs.Proc) bool {
+	var selector = matcher.selector
+
+	if len(selector.Comm) > 0 {
+		var comm, err = proc.Comm()
+		if err != nil || comm != selector.Comm {
+			return false
+		}
+	}
+
+	if matcher.cmdlineRegex != nil {
+		var cmdline, err = proc.Cmdline()
+		if err != nil || !matcher.cmdlineRegex.MatchString(
+			strings.Join(cmdline, " ")) {
+			return false
+		}
+	}
+
+	if len(selector.Exe) > 0 {
+		var exe, err = proc.Exe()
+		if err != nil || exe != selector.Exe {
+			return false
+		}
+	}
+
+	if matcher.uid >= 0 {
+		var status, err = proc.Status()
+		if err != nil || status.UIDs[0] != matcher.uid {
+			return false
+		}
+	}
+
+	if len(selector.Cgroup) > 0 {
+		var cgroups, err = proc.Cgroup()
+		if err != nil || !inCgroup(cgroups, selector.Cgroup) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inCgroup returns true if any of the cgroups in a /proc/<pid>/cgroup file are
+// the provided path or below it
+func inCgroup(cgroups string, path string) bool {
+	path = filepath.Clean("/" + path)
+
+	for _, line := range strings.Split(cgroups, "\n") {
+		var fields = strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		var cgroup = filepath.Clean(fields[2])
+		if cgroup == path || strings.HasPrefix(cgroup, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessSelectorWatch is a Watch that observes a watch for each process
+// matching a selector. The processes are selected again each time it is
+// observed, so processes that restart with a new pid are still watched
+type ProcessSelectorWatch struct {
+	matcher *processMatcher
+	factory func(pid int) core.Watch
+	watches map[int]core.ContextWatch
+}
+
+// NewProcessSelectorWatch creates a new ProcessSelectorWatch that uses the
+// factory to create the watch for each selected process. The factory should
+// return nil if a process cannot be watched. An error is returned if the
+// selector is invalid
+func NewProcessSelectorWatch(fs procfs.FS, selector ProcessSelector,
+	factory func(pid int) core.Watch) (*ProcessSelectorWatch, error) {
+	var matcher, err = newProcessMatcher(fs, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	watch := new(ProcessSelectorWatch)
+	watch.matcher = matcher
+	watch.factory = factory
+	watch.watches = make(map[int]core.ContextWatch)
+	return watch, nil
+}
+
+// Observe the watch for the first selected process with an event
+func (watch *ProcessSelectorWatch) Observe() *core.WatchEvent {
+	return observe("process selector", watch)
+}
+
+// ObserveContext observes the watch for the first selected process with an
+// event. Use ObserveAll to get the events for all the processes
+func (watch *ProcessSelectorWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	var events, err = watch.ObserveAll(ctx)
+	if len(events) > 0 {
+		return events[0], err
+	}
+	return nil, err
+}
+
+// ObserveAll observes the watch for each selected process, returning their
+// events tagged with the pid of the process. Processes that were selected last
+// time but have gone are observed one last time, so their death can be seen
+func (watch *ProcessSelectorWatch) ObserveAll(ctx context.Context) ([]*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var pids, err = watch.matcher.pids()
+	if err != nil {
+		return nil, core.NewWatchError("process selector", err)
+	}
+
+	var selected = make(map[int]bool)
+	for _, pid := range pids {
+		selected[pid] = true
+		if _, ok := watch.watches[pid]; !ok {
+			log.Debug("Selected new process pid=%d", pid)
+			if pidWatch := watch.factory(pid); pidWatch != nil {
+				watch.watches[pid] = core.ContextWatchFor(pidWatch)
+			}
+		}
+	}
+
+	var watched = make([]int, 0, len(watch.watches))
+	for pid := range watch.watches {
+		watched = append(watched, pid)
+	}
+	sort.Ints(watched)
+
+	var events []*core.WatchEvent
+	var firstErr error
+
+	for _, pid := range watched {
+		var pidEvent, pidErr = watch.watches[pid].ObserveContext(ctx)
+
+		if !selected[pid] {
+			log.Debug("Process pid=%d is no longer selected", pid)
+			delete(watch.watches, pid)
+		}
+
+		if pidErr != nil {
+			if !errors.Is(pidErr, ErrProcessNotFound) && firstErr == nil {
+				firstErr = pidErr
+			}
+			continue
+		}
+
+		if pidEvent != nil {
+			if pidEvent.Data == nil {
+				pidEvent.Data = make(map[string]interface{})
+			}
+			pidEvent.Data[ProcessPID] = pid
+			events = append(events, pidEvent)
+		}
+	}
+
+	return events, firstErr
+}
@@ -0,0 +1,120 @@
+package watches
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// processFixture creates a proc filesystem with a few processes
+func processFixture(t *testing.T) procfs.FS {
+	var process = func(comm string, cmdline string, uid string,
+		cgroup string) map[string]string {
+		return map[string]string{
+			"comm":    comm + "\n",
+			"cmdline": cmdline,
+			"status":  "Name:\t" + comm + "\nUid:\t" + uid + "\t" + uid + "\t" + uid + "\t" + uid + "\n",
+			"cgroup":  "0::" + cgroup + "\n",
+		}
+	}
+
+	var files = make(map[string]string)
+	for pid, content := range map[string]map[string]string{
+		"1":   process("init", "/sbin/init\x00", "0", "/init.scope"),
+		"100": process("nginx", "nginx: master process\x00", "0", "/system.slice/nginx.service"),
+		"101": process("nginx", "nginx: worker process\x00", "33", "/system.slice/nginx.service"),
+		"200": process("bash", "-bash\x00", "1000", "/user.slice/user-1000.slice"),
+	} {
+		for name, value := range content {
+			files[filepath.Join(pid, name)] = value
+		}
+	}
+	files["nginx.pid"] = "100\n"
+	files["gone.pid"] = "300\n"
+
+	return procfs.NewFS(writeFixture(t, files))
+}
+
+func TestProcessSelectorPids(t *testing.T) {
+	var fs = processFixture(t)
+
+	var tests = []struct {
+		name     string
+		selector ProcessSelector
+		pids     []int
+	}{
+		{"comm", ProcessSelector{Comm: "nginx"}, []int{100, 101}},
+		{"cmdline", ProcessSelector{Cmdline: "worker"}, []int{101}},
+		{"uid", ProcessSelector{User: "33"}, []int{101}},
+		{"root", ProcessSelector{Comm: "nginx", User: "0"}, []int{100}},
+		{"cgroup", ProcessSelector{Cgroup: "/user.slice"}, []int{200}},
+		{"cgroup prefix", ProcessSelector{Cgroup: "/system"}, nil},
+		{"pidfile", ProcessSelector{PidFile: fs.Path("nginx.pid")}, []int{100}},
+		{"gone", ProcessSelector{PidFile: fs.Path("gone.pid")}, nil},
+		{"none", ProcessSelector{Comm: "sshd"}, nil},
+	}
+
+	for _, test := range tests {
+		var matcher, err = newProcessMatcher(fs, test.selector)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		var pids, pidsErr = matcher.pids()
+		if pidsErr != nil {
+			t.Fatalf("%s: %s", test.name, pidsErr)
+		}
+		if !reflect.DeepEqual(pids, test.pids) {
+			t.Errorf("%s: expected pids %v, got %v", test.name, test.pids, pids)
+		}
+	}
+}
+
+func TestProcessSelectorInvalid(t *testing.T) {
+	var factory = func(pid int) core.Watch { return nil }
+
+	if _, err := NewProcessSelectorWatch(procfs.Default, ProcessSelector{Cmdline: "("},
+		factory); err == nil {
+		t.Error("expected an invalid cmdline regex to be an error")
+	}
+	if _, err := NewProcessSelectorWatch(procfs.Default,
+		ProcessSelector{User: "no-such-user-here"},
+		factory); err == nil {
+		t.Error("expected an unknown user to be an error")
+	}
+}
+
+func TestProcessSelectorWatchIsNotChanged(t *testing.T) {
+	var selector = ProcessSelector{Comm: "nginx", User: "0"}
+	var watch, err = NewProcessSelectorWatch(processFixture(t), selector,
+		func(pid int) core.Watch { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Selecting from several goroutines must not race on the selector
+	var wait sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if pids, err := watch.matcher.pids(); err != nil ||
+				!reflect.DeepEqual(pids, []int{100}) {
+				t.Errorf("expected pid 100, got %v %v", pids, err)
+			}
+		}()
+	}
+	wait.Wait()
+
+	if _, err = watch.ObserveAll(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if watch.matcher.selector != selector {
+		t.Errorf("expected the selector to be unchanged, got %+v",
+			watch.matcher.selector)
+	}
+}
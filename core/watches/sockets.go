@@ -0,0 +1,418 @@
+package watches
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// SocketProtocol is a key in WatchEvent for a socket protocol (tcp or udp)
+var SocketProtocol = "socket.protocol"
+
+// SocketPort is a key in WatchEvent for a local socket port
+var SocketPort = "socket.port"
+
+// SocketListening is a key in WatchEvent for whether a port is listening
+var SocketListening = "socket.listening"
+
+// SocketPids is a key in WatchEvent for the pids of processes owning sockets
+var SocketPids = "socket.pids"
+
+// SocketStates is a key in WatchEvent for the number of sockets in each state
+var SocketStates = "socket.states"
+
+// SocketExceeded is a key in WatchEvent for the socket states with more
+// sockets than their threshold
+var SocketExceeded = "socket.exceeded"
+
+// SocketList is a key in WatchEvent for a list of Sockets
+var SocketList = "socket.list"
+
+// The socket states in /proc/net/tcp
+var (
+	SocketEstablished = "ESTABLISHED"
+	SocketSynSent     = "SYN_SENT"
+	SocketSynRecv     = "SYN_RECV"
+	SocketFinWait1    = "FIN_WAIT1"
+	SocketFinWait2    = "FIN_WAIT2"
+	SocketTimeWait    = "TIME_WAIT"
+	SocketClose       = "CLOSE"
+	SocketCloseWait   = "CLOSE_WAIT"
+	SocketLastAck     = "LAST_ACK"
+	SocketListen      = "LISTEN"
+	SocketClosing     = "CLOSING"
+)
+
+var socketStates = map[string]string{
+	"01": SocketEstablished,
+	"02": SocketSynSent,
+	"03": SocketSynRecv,
+	"04": SocketFinWait1,
+	"05": SocketFinWait2,
+	"06": SocketTimeWait,
+	"07": SocketClose,
+	"08": SocketCloseWait,
+	"09": SocketLastAck,
+	"0A": SocketListen,
+	"0B": SocketClosing,
+}
+
+// Socket is an entry in one of the /proc/net socket tables
+type Socket struct {
+	Protocol   string
+	LocalIP    net.IP
+	LocalPort  int
+	RemoteIP   net.IP
+	RemotePort int
+	State      string
+	UID        int
+	Inode      uint64
+	// PID is the process owning the socket, 0 if it is not known
+	PID int
+}
+
+// Listening returns true if the socket is listening for connections, or for
+// UDP is bound without a remote address
+func (socket Socket) Listening() bool {
+	if strings.HasPrefix(socket.Protocol, "udp") {
+		return socket.State == SocketClose && socket.RemotePort == 0
+	}
+	return socket.State == SocketListen
+}
+
+func (socket Socket) String() string {
+	var owner = ""
+	if socket.PID > 0 {
+		owner = fmt.Sprintf(" pid=%d", socket.PID)
+	}
+	return fmt.Sprintf("%s %s -> %s %s%s", socket.Protocol,
+		net.JoinHostPort(socket.LocalIP.String(), strconv.Itoa(socket.LocalPort)),
+		net.JoinHostPort(socket.RemoteIP.String(), strconv.Itoa(socket.RemotePort)),
+		socket.State, owner)
+}
+
+// socketTables gets the /proc/net tables for a protocol, tcp or udp covering
+// IPv4 and IPv6
+func socketTables(protocol string) []string {
+	switch protocol {
+	case "tcp", "udp":
+		return []string{protocol, protocol + "6"}
+	}
+	return []string{protocol}
+}
+
+// ReadSockets reads the sockets for a protocol (tcp, udp, tcp6 or udp6). If
+// withOwners is true the pid owning each socket is found, which needs access to
+// the fds of other processes
+func ReadSockets(protocol string, withOwners bool) ([]Socket, error) {
+	return ReadSocketsFrom(procfs.Default, protocol, withOwners)
+}
+
+// ReadSocketsFrom reads the sockets for a protocol from the provided proc
+// filesystem, as ReadSockets does
+func ReadSocketsFrom(fs procfs.FS, protocol string,
+	withOwners bool) ([]Socket, error) {
+	var sockets []Socket
+
+	for _, table := range socketTables(protocol) {
+		var tableStr, err = fs.NetTable(table)
+		if err != nil {
+			if utils.PathExists(fs.Path("net", table)) {
+				return nil, err
+			}
+			// IPv6 may not be available
+			continue
+		}
+
+		var lines = strings.Split(tableStr, "\n")
+		for _, line := range lines[1:] {
+			var fields = strings.Fields(line)
+			if len(fields) < 10 {
+				continue
+			}
+
+			var socket, parseErr = parseSocket(table, fields)
+			if parseErr != nil {
+				return nil, fmt.Errorf("invalid %s socket %q: %s", table, line,
+					parseErr)
+			}
+			sockets = append(sockets, socket)
+		}
+	}
+
+	if withOwners {
+		var owners, err = fs.SocketOwners()
+		if err != nil {
+			return nil, err
+		}
+		for i := range sockets {
+			sockets[i].PID = owners[sockets[i].Inode]
+		}
+	}
+
+	return sockets, nil
+}
+
+// parseSocket parses the fields of a line in a /proc/net socket table
+func parseSocket(table string, fields []string) (Socket, error) {
+	var socket = Socket{Protocol: table}
+	var err error
+
+	if socket.LocalIP, socket.LocalPort, err = parseSocketAddress(fields[1]); err != nil {
+		return socket, err
+	}
+	if socket.RemoteIP, socket.RemotePort, err = parseSocketAddress(fields[2]); err != nil {
+		return socket, err
+	}
+
+	var ok bool
+	if socket.State, ok = socketStates[fields[3]]; !ok {
+		socket.State = fields[3]
+	}
+
+	if socket.UID, err = strconv.Atoi(fields[7]); err != nil {
+		return socket, err
+	}
+	if socket.Inode, err = strconv.ParseUint(fields[9], 10, 64); err != nil {
+		return socket, err
+	}
+
+	return socket, nil
+}
+
+// parseSocketAddress parses an address like 0100007F:0050. The address is
+// written as 32 bit words in host byte order, the port in network byte order
+func parseSocketAddress(address string) (net.IP, int, error) {
+	var parts = strings.Split(address, ":")
+	if len(parts) != 2 {
+		return nil, 0, fmt.Errorf("invalid address %s", address)
+	}
+
+	var raw, err = hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %s", address)
+	}
+
+	var ip = make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.NativeEndian.PutUint32(ip[i:], binary.BigEndian.Uint32(raw[i:]))
+	}
+
+	var port, portErr = strconv.ParseUint(parts[1], 16, 16)
+	if portErr != nil {
+		return nil, 0, portErr
+	}
+
+	return ip, int(port), nil
+}
+
+// PortListeningWatch is a Watch that observes whether a local port is
+// listening
+type PortListeningWatch struct {
+	fs        procfs.FS
+	protocol  string
+	port      int
+	listening bool
+}
+
+// NewPortListeningWatch creates a new PortListeningWatch that reports when a
+// tcp or udp port is not listening, or is listening when listening is false
+func NewPortListeningWatch(fs procfs.FS, protocol string, port int,
+	listening bool) *PortListeningWatch {
+	watch := new(PortListeningWatch)
+	watch.fs = fs
+	watch.protocol = protocol
+	watch.port = port
+	watch.listening = listening
+	return watch
+}
+
+// Observe whether the port is not in the expected state
+func (watch *PortListeningWatch) Observe() *core.WatchEvent {
+	return observe("port "+strconv.Itoa(watch.port), watch)
+}
+
+// ObserveContext observes whether the port is listening, returning an event if
+// that is not what is expected
+func (watch *PortListeningWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var sockets, err = ReadSocketsFrom(watch.fs, watch.protocol, !watch.listening)
+	if err != nil {
+		return nil, core.NewWatchError(watch.protocol, err)
+	}
+
+	var listening = false
+	var pids []int
+	for _, socket := range sockets {
+		if socket.LocalPort == watch.port && socket.Listening() {
+			listening = true
+			if socket.PID > 0 {
+				pids = append(pids, socket.PID)
+			}
+		}
+	}
+
+	if listening == watch.listening {
+		return nil, nil
+	}
+
+	var data = map[string]interface{}{
+		SocketProtocol:  watch.protocol,
+		SocketPort:      watch.port,
+		SocketListening: listening,
+	}
+	if len(pids) > 0 {
+		data[SocketPids] = pids
+	}
+	return core.NewWatchEvent(data), nil
+}
+
+// ConnectionStateWatch is a Watch that counts the sockets on a local port in
+// each state
+type ConnectionStateWatch struct {
+	fs         procfs.FS
+	protocol   string
+	port       int
+	thresholds map[string]int
+}
+
+// NewConnectionStateWatch creates a new ConnectionStateWatch that reports when
+// the number of sockets in a state (e.g. TIME_WAIT) is above its threshold. A
+// port of 0 counts the sockets on all ports
+func NewConnectionStateWatch(fs procfs.FS, protocol string, port int,
+	thresholds map[string]int) *ConnectionStateWatch {
+	watch := new(ConnectionStateWatch)
+	watch.fs = fs
+	watch.protocol = protocol
+	watch.port = port
+	watch.thresholds = thresholds
+	return watch
+}
+
+// Observe whether there are too many sockets in a state
+func (watch *ConnectionStateWatch) Observe() *core.WatchEvent {
+	return observe("connections "+strconv.Itoa(watch.port), watch)
+}
+
+// ObserveContext counts the sockets in each state, returning an event if any
+// are above their threshold
+func (watch *ConnectionStateWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var sockets, err = ReadSocketsFrom(watch.fs, watch.protocol, false)
+	if err != nil {
+		return nil, core.NewWatchError(watch.protocol, err)
+	}
+
+	var states = make(map[string]int)
+	for _, socket := range sockets {
+		if watch.port == 0 || socket.LocalPort == watch.port {
+			states[socket.State]++
+		}
+	}
+
+	var exceeded []string
+	for state, threshold := range watch.thresholds {
+		if states[state] > threshold {
+			exceeded = append(exceeded, state)
+		}
+	}
+
+	if len(exceeded) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(exceeded)
+	return core.NewWatchEvent(map[string]interface{}{
+		SocketProtocol: watch.protocol,
+		SocketPort:     watch.port,
+		SocketStates:   states,
+		SocketExceeded: exceeded,
+	}), nil
+}
+
+// ProcessSocketsWatch is a Watch that observes the sockets held by a process
+type ProcessSocketsWatch struct {
+	fs     procfs.FS
+	pid    int
+	inodes map[uint64]bool
+}
+
+// NewProcessSocketsWatch returns a new ProcessSocketsWatch for the provided pid
+func NewProcessSocketsWatch(fs procfs.FS, pid int) *ProcessSocketsWatch {
+	watch := new(ProcessSocketsWatch)
+	watch.fs = fs
+	watch.pid = pid
+	return watch
+}
+
+// Observe whether the sockets held by the process have changed
+func (watch *ProcessSocketsWatch) Observe() *core.WatchEvent {
+	return observe("process sockets", watch)
+}
+
+// ObserveContext observes the sockets held by the process, returning an event
+// listing them when they have changed
+func (watch *ProcessSocketsWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var proc = watch.fs.Proc(watch.pid)
+	var inodes, err = proc.SocketInodes()
+	if err != nil {
+		if !proc.Exists() {
+			err = ErrProcessNotFound
+		}
+		return nil, core.NewWatchError("process sockets", err)
+	}
+
+	var held = make(map[uint64]bool)
+	for _, inode := range inodes {
+		held[inode] = true
+	}
+
+	var changed = len(held) != len(watch.inodes) || watch.inodes == nil
+	for inode := range held {
+		if !watch.inodes[inode] {
+			changed = true
+		}
+	}
+	watch.inodes = held
+
+	if !changed {
+		return nil, nil
+	}
+
+	var list []Socket
+	for _, protocol := range []string{"tcp", "udp"} {
+		var sockets, readErr = ReadSocketsFrom(watch.fs, protocol, false)
+		if readErr != nil {
+			return nil, core.NewWatchError("process sockets", readErr)
+		}
+		for _, socket := range sockets {
+			if held[socket.Inode] {
+				socket.PID = watch.pid
+				list = append(list, socket)
+			}
+		}
+	}
+
+	return core.NewWatchEvent(map[string]interface{}{
+		SocketList: list,
+	}), nil
+}
@@ -0,0 +1,87 @@
+package watches
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+var tcpTable = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1234 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0050 0100007F:D431 01 00000000:00000000 00:00000000 00000000  1000        0 5678 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:1F90 0100007F:D432 06 00000000:00000000 03:00000000 00000000     0        0 0 3 0000000000000000
+`
+
+// socketsFixture creates a proc filesystem with a tcp table and a process
+// holding its sockets
+func socketsFixture(t *testing.T) procfs.FS {
+	var root = writeFixture(t, map[string]string{
+		"net/tcp":  tcpTable,
+		"net/tcp6": "  sl  local_address remote_address st\n",
+	})
+
+	var fdDir = filepath.Join(root, "42", "fd")
+	if err := os.MkdirAll(fdDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for fd, target := range map[string]string{"3": "socket:[1234]", "4": "socket:[5678]"} {
+		if err := os.Symlink(target, filepath.Join(fdDir, fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return procfs.NewFS(root)
+}
+
+func TestReadSocketsFrom(t *testing.T) {
+	var fs = socketsFixture(t)
+
+	var sockets, err = ReadSocketsFrom(fs, "tcp", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sockets) != 3 {
+		t.Fatalf("expected 3 sockets, got %d", len(sockets))
+	}
+
+	var listen = sockets[0]
+	if !listen.Listening() || listen.LocalPort != 80 ||
+		!listen.LocalIP.Equal([]byte{127, 0, 0, 1}) {
+		t.Errorf("expected 127.0.0.1:80 to be listening, got %s", listen)
+	}
+	if listen.UID != 1000 || listen.Inode != 1234 || listen.PID != 42 {
+		t.Errorf("expected uid 1000 inode 1234 pid 42, got %d %d %d",
+			listen.UID, listen.Inode, listen.PID)
+	}
+
+	if sockets[1].State != SocketEstablished || sockets[1].RemotePort != 54321 {
+		t.Errorf("expected an established connection from port 54321, got %s",
+			sockets[1])
+	}
+	if sockets[2].State != SocketTimeWait || sockets[2].PID != 0 {
+		t.Errorf("expected an unowned TIME_WAIT socket, got %s", sockets[2])
+	}
+}
+
+func TestProcessSocketsWatchUsesFS(t *testing.T) {
+	var watch = NewProcessSocketsWatch(socketsFixture(t), 42)
+
+	var event, err = watch.ObserveContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event == nil {
+		t.Fatal("expected the sockets held to be reported")
+	}
+	if list := event.Get(SocketList).([]Socket); len(list) != 2 {
+		t.Errorf("expected 2 sockets, got %v", list)
+	}
+
+	watch.pid = 43
+	if _, err = watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error for a missing process")
+	}
+}
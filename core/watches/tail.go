@@ -0,0 +1,317 @@
+package watches
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// TailFile is a key in WatchEvent for the name of the file being tailed
+var TailFile = "tail.file"
+
+// TailLine is a key in WatchEvent for a line read from a tailed file
+var TailLine = "tail.line"
+
+// TailMatch is a key in WatchEvent for the text matching a tail pattern
+var TailMatch = "tail.match"
+
+// TailOffset is a key in WatchEvent for the offset after a line in a file
+var TailOffset = "tail.offset"
+
+// tailReadSize is the most that is read from a file in one observation, so a
+// file that grows fast, or is read from the start, is read in batches rather
+// than all at once
+var tailReadSize int64 = 1 << 20
+
+// tailMaxLine is the length of the longest line. Longer lines are split
+var tailMaxLine = 64 * 1024
+
+// TailWatch is a Watch that follows a file like tail -F, producing an event for
+// each new line, or for each match of a pattern. It carries on following the
+// file when it is rotated by renaming or truncating it
+type TailWatch struct {
+	fileName  string
+	pattern   *regexp.Regexp
+	stateFile string
+	file      *os.File
+	inode     uint64
+	offset    int64
+	partial   []byte
+	pending   []*core.WatchEvent
+	saved     bool
+	savedNode uint64
+	savedAt   int64
+}
+
+// NewTailWatch creates a new TailWatch for the provided file. If pattern is
+// not empty only lines matching it produce events, with any named groups added
+// to the event. If stateFile is not empty the offset reached in the file is
+// saved there, so lines are not missed or repeated across restarts
+func NewTailWatch(file string, pattern string, stateFile string) *TailWatch {
+	watch := new(TailWatch)
+	watch.fileName = file
+	watch.stateFile = stateFile
+
+	if len(pattern) > 0 {
+		var re, err = regexp.Compile(pattern)
+		if err != nil {
+			log.Error("Invalid tail pattern %s : %s", pattern, err)
+			return nil
+		}
+		watch.pattern = re
+	}
+
+	// Start from the saved offset, or the end of the file if there isn't one.
+	// If the file has been replaced since the offset was saved then all of it
+	// is new
+	var inode, offset, ok = watch.loadState()
+	if ok {
+		watch.saved = true
+		watch.savedNode = inode
+		watch.savedAt = offset
+	}
+	if err := watch.open(); err == nil {
+		var info, statErr = watch.file.Stat()
+		if ok && inode == watch.inode && statErr == nil &&
+			offset <= info.Size() {
+			watch.offset = offset
+		} else if !ok && statErr == nil {
+			watch.offset = info.Size()
+		}
+	} else {
+		log.Debug("Cannot open %s yet : %s", file, err)
+	}
+
+	return watch
+}
+
+// Observe the next line from the file
+func (watch *TailWatch) Observe() *core.WatchEvent {
+	return observe(watch.fileName, watch)
+}
+
+// ObserveContext observes the next line from the file. Any other lines read
+// are returned by later observations
+func (watch *TailWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if len(watch.pending) == 0 {
+		var events, err = watch.ObserveAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		watch.pending = events
+	}
+
+	if len(watch.pending) == 0 {
+		return nil, nil
+	}
+
+	var event = watch.pending[0]
+	watch.pending = watch.pending[1:]
+	return event, nil
+}
+
+// ObserveAll observes all the lines added to the file since it was last
+// observed
+func (watch *TailWatch) ObserveAll(ctx context.Context) ([]*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var events = watch.pending
+	watch.pending = nil
+
+	if watch.file == nil {
+		if err := watch.open(); err != nil {
+			if os.IsNotExist(err) {
+				return events, nil
+			}
+			return events, core.NewWatchError(watch.fileName, err)
+		}
+	}
+
+	// Read whatever is left in the open file
+	var lines, more, err = watch.read()
+	events = append(events, lines...)
+	if err != nil {
+		return events, core.NewWatchError(watch.fileName, err)
+	}
+	if more {
+		// Finish reading the file before checking whether it was rotated
+		watch.saveState()
+		return events, nil
+	}
+
+	// Check whether the file has been rotated
+	var info, statErr = os.Stat(watch.fileName)
+	if os.IsNotExist(statErr) {
+		// Renamed and not replaced yet, carry on reading the old one
+		watch.saveState()
+		return events, nil
+	} else if statErr != nil {
+		return events, core.NewWatchError(watch.fileName, statErr)
+	}
+
+	if utils.FileInode(info) != watch.inode {
+		log.Debug("%s has been replaced, reopening", watch.fileName)
+		if len(watch.partial) > 0 {
+			events = append(events, watch.lineEvents(string(watch.partial),
+				watch.offset)...)
+		}
+		watch.file.Close()
+		watch.file = nil
+		watch.partial = nil
+
+		if err := watch.open(); err != nil {
+			return events, core.NewWatchError(watch.fileName, err)
+		}
+		lines, _, err = watch.read()
+		events = append(events, lines...)
+	} else if info.Size() < watch.offset {
+		log.Debug("%s has been truncated, reading from the start",
+			watch.fileName)
+		watch.offset = 0
+		watch.partial = nil
+		lines, _, err = watch.read()
+		events = append(events, lines...)
+	}
+
+	watch.saveState()
+
+	if err != nil {
+		return events, core.NewWatchError(watch.fileName, err)
+	}
+	return events, nil
+}
+
+// open the file, reading from the start of it
+func (watch *TailWatch) open() error {
+	var file, err = os.Open(watch.fileName)
+	if err != nil {
+		return err
+	}
+
+	var info, statErr = file.Stat()
+	if statErr != nil {
+		file.Close()
+		return statErr
+	}
+
+	watch.file = file
+	watch.inode = utils.FileInode(info)
+	watch.offset = 0
+	return nil
+}
+
+// read the complete lines from the current offset in the file, up to
+// tailReadSize bytes. more is true if the file may have more to read
+func (watch *TailWatch) read() ([]*core.WatchEvent, bool, error) {
+	var events []*core.WatchEvent
+	var start = watch.offset
+	var reader = bufio.NewReaderSize(
+		io.NewSectionReader(watch.file, start, tailReadSize), tailMaxLine)
+	var offset = watch.offset - int64(len(watch.partial))
+
+	for {
+		var chunk, readErr = reader.ReadSlice('\n')
+		watch.offset += int64(len(chunk))
+		watch.partial = append(watch.partial, chunk...)
+
+		switch readErr {
+		case nil:
+			offset += int64(len(watch.partial))
+			var line = bytes.TrimRight(watch.partial[:len(watch.partial)-1], "\r")
+			events = append(events, watch.lineEvents(string(line), offset)...)
+			watch.partial = watch.partial[:0]
+		case bufio.ErrBufferFull:
+			if len(watch.partial) >= tailMaxLine {
+				offset += int64(len(watch.partial))
+				events = append(events, watch.lineEvents(string(watch.partial),
+					offset)...)
+				watch.partial = watch.partial[:0]
+			}
+		case io.EOF:
+			return events, watch.offset-start == tailReadSize, nil
+		default:
+			return events, false, readErr
+		}
+	}
+}
+
+// lineEvents gets the events for a line read from the file
+func (watch *TailWatch) lineEvents(line string, offset int64) []*core.WatchEvent {
+	if watch.pattern == nil {
+		return []*core.WatchEvent{core.NewWatchEvent(map[string]interface{}{
+			TailFile:   watch.fileName,
+			TailLine:   line,
+			TailOffset: offset,
+		})}
+	}
+
+	var events []*core.WatchEvent
+	var names = watch.pattern.SubexpNames()
+
+	for _, match := range watch.pattern.FindAllStringSubmatch(line, -1) {
+		var data = map[string]interface{}{
+			TailFile:   watch.fileName,
+			TailLine:   line,
+			TailMatch:  match[0],
+			TailOffset: offset,
+		}
+		for i, name := range names {
+			if len(name) > 0 {
+				data[name] = match[i]
+			}
+		}
+		events = append(events, core.NewWatchEvent(data))
+	}
+	return events
+}
+
+// loadState gets the inode and offset saved in the state file
+func (watch *TailWatch) loadState() (uint64, int64, bool) {
+	if len(watch.stateFile) == 0 {
+		return 0, 0, false
+	}
+
+	var state, err = utils.GetFileAsString(watch.stateFile)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	var inode uint64
+	var offset int64
+	if _, err := fmt.Sscanf(state, "%d %d", &inode, &offset); err != nil {
+		log.Warn("Invalid tail state in %s : %s", watch.stateFile, err)
+		return 0, 0, false
+	}
+	return inode, offset, true
+}
+
+// saveState saves the inode and offset of the complete lines read
+func (watch *TailWatch) saveState() {
+	if len(watch.stateFile) == 0 {
+		return
+	}
+
+	// Only write the state when it has changed
+	var offset = watch.offset - int64(len(watch.partial))
+	if watch.saved && watch.savedNode == watch.inode && watch.savedAt == offset {
+		return
+	}
+
+	var state = fmt.Sprintf("%d %d\n", watch.inode, offset)
+	if err := utils.WriteFileAtomic(watch.stateFile, []byte(state), 0644); err != nil {
+		log.Warn("Failed to save tail state to %s : %s", watch.stateFile, err)
+		return
+	}
+	watch.saved = true
+	watch.savedNode = watch.inode
+	watch.savedAt = offset
+}
@@ -0,0 +1,216 @@
+package watches
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// appendFile appends to a file
+func appendFile(t *testing.T, path string, content string) {
+	t.Helper()
+	var file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err = file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tailLines observes all the lines added to a tailed file
+func tailLines(t *testing.T, watch *TailWatch) []string {
+	t.Helper()
+	var events, err = watch.ObserveAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, event := range events {
+		lines = append(lines, event.GetAsString(TailLine))
+	}
+	return lines
+}
+
+func checkLines(t *testing.T, watch *TailWatch, expected ...string) {
+	t.Helper()
+	if lines := tailLines(t, watch); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected lines %q, got %q", expected, lines)
+	}
+}
+
+func TestTailWatchAppend(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, file, "old line\n")
+
+	// Lines already in the file are not reported
+	var watch = NewTailWatch(file, "", "")
+	checkLines(t, watch)
+
+	appendFile(t, file, "one\r\ntwo\nthr")
+	checkLines(t, watch, "one", "two")
+
+	// Partial lines are reported once they are complete
+	appendFile(t, file, "ee\n")
+	checkLines(t, watch, "three")
+	checkLines(t, watch)
+}
+
+func TestTailWatchRotation(t *testing.T) {
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "app.log")
+	writeFile(t, file, "")
+
+	var watch = NewTailWatch(file, "", "")
+	appendFile(t, file, "one\n")
+	checkLines(t, watch, "one")
+
+	// Lines written to the old file after it was renamed are still read
+	if err := os.Rename(file, file+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, file+".1", "two\n")
+	checkLines(t, watch, "two")
+
+	appendFile(t, file+".1", "three\n")
+	appendFile(t, file, "four\n")
+	checkLines(t, watch, "three", "four")
+
+	appendFile(t, file, "five\n")
+	checkLines(t, watch, "five")
+}
+
+func TestTailWatchCopyTruncate(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, file, "")
+
+	var watch = NewTailWatch(file, "", "")
+	appendFile(t, file, "one\ntwo\n")
+	checkLines(t, watch, "one", "two")
+
+	if err := os.Truncate(file, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, file, "three\n")
+	checkLines(t, watch, "three")
+}
+
+func TestTailWatchMatch(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, file, "")
+
+	var watch = NewTailWatch(file, `(?P<level>ERROR|WARN): (?P<code>\d+)`, "")
+	appendFile(t, file, "INFO: 1\nERROR: 500 and WARN: 404\nWARN: 301\n")
+
+	var events, err = watch.ObserveAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []map[string]string{
+		{TailMatch: "ERROR: 500", "level": "ERROR", "code": "500"},
+		{TailMatch: "WARN: 404", "level": "WARN", "code": "404"},
+		{TailMatch: "WARN: 301", "level": "WARN", "code": "301"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d matches, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		for key, value := range expected[i] {
+			if event.Get(key) != value {
+				t.Errorf("match %d: expected %s=%s, got %v", i, key, value,
+					event.Get(key))
+			}
+		}
+	}
+
+	if NewTailWatch(file, "(", "") != nil {
+		t.Error("expected an invalid pattern to not be watched")
+	}
+}
+
+func TestTailWatchState(t *testing.T) {
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "app.log")
+	var state = filepath.Join(dir, "app.state")
+	writeFile(t, file, "old\n")
+
+	var watch = NewTailWatch(file, "", state)
+	appendFile(t, file, "one\ntw")
+	checkLines(t, watch, "one")
+
+	// Lines added while not running are read from the saved offset, including
+	// the partial line
+	appendFile(t, file, "o\nthree\n")
+	watch = NewTailWatch(file, "", state)
+	checkLines(t, watch, "two", "three")
+
+	// A file replaced while not running is read from the start
+	if err := os.Rename(file, file+".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, file, "new\n")
+	watch = NewTailWatch(file, "", state)
+	checkLines(t, watch, "new")
+}
+
+func TestTailWatchBatches(t *testing.T) {
+	var readSize, maxLine = tailReadSize, tailMaxLine
+	tailReadSize, tailMaxLine = 32, 16
+	defer func() { tailReadSize, tailMaxLine = readSize, maxLine }()
+
+	var file = filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, file, "")
+	var watch = NewTailWatch(file, "", "")
+
+	// Only a batch is read each time, and long lines are split
+	var long = strings.Repeat("x", 20)
+	appendFile(t, file, "0123456789\n"+long+"\nabcdefghij\nklm\n")
+	checkLines(t, watch, "0123456789", long[:16], long[16:])
+	checkLines(t, watch, "abcdefghij", "klm")
+
+	// A renamed file is finished before the new one is read
+	appendFile(t, file, strings.Repeat("line\n", 10))
+	if err := os.Rename(file, file+".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, file, "new\n")
+
+	var lines []string
+	for i := 0; i < 5; i++ {
+		lines = append(lines, tailLines(t, watch)...)
+	}
+	var expected = append(strings.Split(strings.Repeat("line\n", 10), "\n")[:10], "new")
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected lines %q, got %q", expected, lines)
+	}
+}
+
+func TestTailWatchObserveContext(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "app.log")
+	var watch = NewTailWatch(file, "", "")
+
+	// The file does not need to exist yet
+	if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+		t.Fatalf("expected nothing before the file exists, got %v %v", event, err)
+	}
+
+	writeFile(t, file, "one\ntwo\n")
+	for _, expected := range []string{"one", "two"} {
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil || event == nil || event.Get(TailLine) != expected {
+			t.Fatalf("expected line %s, got %v %v", expected, event, err)
+		}
+	}
+	var event *core.WatchEvent
+	if event, _ = watch.ObserveContext(context.Background()); event != nil {
+		t.Errorf("expected no more lines, got %v", event.Data)
+	}
+}
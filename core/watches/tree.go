@@ -0,0 +1,285 @@
+package watches
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
+	"github.com/deanydean/clockwork/core/utils"
+)
+
+// TreePID is a key in WatchEvent for the pid at the root of a process tree
+var TreePID = "tree.pid"
+
+// TreeCPU is a key in WatchEvent for the CPU usage of a process tree over the
+// interval since it was last observed, as a percentage of one core
+var TreeCPU = "tree.cpu"
+
+// TreeRSS is a key in WatchEvent for the resident memory of a process tree in
+// bytes
+var TreeRSS = "tree.rss"
+
+// TreeReadsPerSec is a key in WatchEvent for the bytes read per second by a
+// process tree
+var TreeReadsPerSec = "tree.reads_per_sec"
+
+// TreeWritesPerSec is a key in WatchEvent for the bytes written per second by
+// a process tree
+var TreeWritesPerSec = "tree.writes_per_sec"
+
+// TreeCount is a key in WatchEvent for the number of processes in a process
+// tree
+var TreeCount = "tree.count"
+
+// TreeProcesses is a key in WatchEvent for the processes in a process tree,
+// as a []ProcessTreeMember
+var TreeProcesses = "tree.processes"
+
+// TreeExceeded is a key in WatchEvent for the thresholds that were exceeded
+var TreeExceeded = "tree.exceeded"
+
+// ProcessTreeThresholds are the values above which a ProcessTreeWatch reports
+// a process tree. Thresholds that are 0 are not checked
+type ProcessTreeThresholds struct {
+	CPU          float64
+	RSS          int64
+	ReadsPerSec  float64
+	WritesPerSec float64
+	Count        int
+}
+
+// ProcessTreeMember is the resource usage of one process in a process tree
+type ProcessTreeMember struct {
+	PID          int
+	PPID         int
+	Comm         string
+	CPU          float64
+	RSS          int64
+	ReadsPerSec  float64
+	WritesPerSec float64
+}
+
+// treeSample is the counters of a process when it was last observed
+type treeSample struct {
+	startTime    int64
+	cpuTicks     int64
+	readBytes    int64
+	writeBytes   int64
+	hasIO        bool
+	observedTime time.Time
+}
+
+// ProcessTreeWatch is a Watch that observes the resources used by a process
+// and all its descendants
+type ProcessTreeWatch struct {
+	fs              procfs.FS
+	pid             int
+	thresholds      ProcessTreeThresholds
+	sysClockTick    int
+	pageSize        int
+	samples         map[int]treeSample
+	lastObservation time.Time
+}
+
+// NewProcessTreeWatch creates a new ProcessTreeWatch for the process tree
+// rooted at pid in the proc filesystem, reporting when its total usage
+// exceeds the thresholds
+func NewProcessTreeWatch(fs procfs.FS, pid int,
+	thresholds ProcessTreeThresholds) *ProcessTreeWatch {
+	watch := new(ProcessTreeWatch)
+	watch.fs = fs
+	watch.pid = pid
+	watch.thresholds = thresholds
+	watch.sysClockTick = utils.GetSystemClockTick()
+	watch.pageSize = utils.GetPageSize()
+
+	if watch.sysClockTick == -1 || watch.pageSize == -1 {
+		log.Warn("Failed to get system clock speed or page size, cannot create watch")
+		return nil
+	}
+
+	// Init the watch with an initial value
+	watch.Observe()
+
+	return watch
+}
+
+// Observe whether a process tree is using too many resources
+func (watch *ProcessTreeWatch) Observe() *core.WatchEvent {
+	return observe("process tree", watch)
+}
+
+// ObserveContext observes the usage of every process in the tree, returning
+// an event if the totals since the last observation exceed the thresholds
+func (watch *ProcessTreeWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !watch.fs.Proc(watch.pid).Exists() {
+		return nil, core.NewWatchError("process tree", ErrProcessNotFound)
+	}
+
+	var now = time.Now()
+	var lastSamples = watch.samples
+	var samples = make(map[int]treeSample)
+	var members []ProcessTreeMember
+
+	for _, pid := range processTree(watch.fs, watch.pid) {
+		var member, sample, err = watch.sampleProcess(pid)
+		if err != nil {
+			// The process has gone since the tree was read
+			log.Debug("Failed to read process %d in tree : %s", pid, err)
+			continue
+		}
+		sample.observedTime = now
+
+		if last, ok := lastSamples[pid]; ok && last.startTime == sample.startTime {
+			var since = now.Sub(last.observedTime).Seconds()
+			if since > 0 {
+				member.CPU = float64(sample.cpuTicks-last.cpuTicks) /
+					float64(watch.sysClockTick) / since * 100
+				if sample.hasIO && last.hasIO {
+					member.ReadsPerSec = float64(sample.readBytes-last.readBytes) / since
+					member.WritesPerSec = float64(sample.writeBytes-last.writeBytes) / since
+				}
+			}
+		} else if lastSamples != nil {
+			// The process started since the last observation, so all its
+			// usage happened in this interval
+			var since = now.Sub(watch.lastObservation).Seconds()
+			if since > 0 {
+				member.CPU = float64(sample.cpuTicks) /
+					float64(watch.sysClockTick) / since * 100
+				if sample.hasIO {
+					member.ReadsPerSec = float64(sample.readBytes) / since
+					member.WritesPerSec = float64(sample.writeBytes) / since
+				}
+			}
+		}
+
+		samples[pid] = sample
+		members = append(members, member)
+	}
+
+	watch.samples = samples
+	var lastObservation = watch.lastObservation
+	watch.lastObservation = now
+
+	if lastSamples == nil || lastObservation.IsZero() {
+		// Nothing to compare with yet
+		return nil, nil
+	}
+
+	var cpu, readsPerSec, writesPerSec float64
+	var rss int64
+	for _, member := range members {
+		cpu += member.CPU
+		rss += member.RSS
+		readsPerSec += member.ReadsPerSec
+		writesPerSec += member.WritesPerSec
+	}
+
+	log.Debug("tree=%d processes=%d cpu=%f rss=%d read/s=%f write/s=%f",
+		watch.pid, len(members), cpu, rss, readsPerSec, writesPerSec)
+
+	var thresholds = watch.thresholds
+	var exceeded = exceededThresholds(map[string][2]float64{
+		TreeCPU:          {cpu, thresholds.CPU},
+		TreeRSS:          {float64(rss), float64(thresholds.RSS)},
+		TreeReadsPerSec:  {readsPerSec, thresholds.ReadsPerSec},
+		TreeWritesPerSec: {writesPerSec, thresholds.WritesPerSec},
+		TreeCount:        {float64(len(members)), float64(thresholds.Count)},
+	})
+
+	if len(exceeded) == 0 {
+		// Nothing to report
+		return nil, nil
+	}
+
+	return core.NewWatchEvent(map[string]interface{}{
+		TreePID:          watch.pid,
+		TreeCPU:          cpu,
+		TreeRSS:          rss,
+		TreeReadsPerSec:  readsPerSec,
+		TreeWritesPerSec: writesPerSec,
+		TreeCount:        len(members),
+		TreeProcesses:    members,
+		TreeExceeded:     exceeded,
+	}), nil
+}
+
+// sampleProcess reads the usage counters of a process in the tree. The IO
+// counters are left out if they cannot be read, as they need more privileges
+// than the stats
+func (watch *ProcessTreeWatch) sampleProcess(pid int) (ProcessTreeMember,
+	treeSample, error) {
+	var member = ProcessTreeMember{PID: pid}
+	var sample treeSample
+
+	var proc = watch.fs.Proc(pid)
+	var stat, err = proc.Stat()
+	if err != nil {
+		return member, sample, err
+	}
+
+	member.Comm = stat.Comm
+	member.PPID = stat.PPID
+	member.RSS = stat.RSS * int64(watch.pageSize)
+	sample.cpuTicks = int64(stat.UTime + stat.STime)
+	sample.startTime = int64(stat.StartTime)
+
+	if counters, ioErr := proc.IO(); ioErr == nil {
+		sample.readBytes = int64(counters.ReadBytes)
+		sample.writeBytes = int64(counters.WriteBytes)
+		sample.hasIO = true
+	}
+
+	return member, sample, nil
+}
+
+// processTree returns the pid of a process and all its descendants. The
+// children files are used where the kernel has them, otherwise the parent of
+// every process is read
+func processTree(fs procfs.FS, pid int) []int {
+	var tree = []int{pid}
+	var parents map[int]int
+
+	for i := 0; i < len(tree); i++ {
+		var children, err = fs.Proc(tree[i]).Children()
+		if err != nil {
+			if parents == nil {
+				parents = processParents(fs)
+			}
+			children = nil
+			for child, parent := range parents {
+				if parent == tree[i] {
+					children = append(children, child)
+				}
+			}
+			sort.Ints(children)
+		}
+		tree = append(tree, children...)
+	}
+
+	return tree
+}
+
+// processParents gets the parent pid of each process, read from their stat
+// files. Processes that cannot be read are not included
+func processParents(fs procfs.FS) map[int]int {
+	var pids, err = fs.Pids()
+	if err != nil {
+		log.Warn("Failed to read pids : %s", err)
+	}
+
+	var parents = make(map[int]int)
+	for _, pid := range pids {
+		if stat, statErr := fs.Proc(pid).Stat(); statErr == nil {
+			parents[pid] = stat.PPID
+		}
+	}
+	return parents
+}
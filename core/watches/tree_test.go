@@ -0,0 +1,196 @@
+package watches
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core/procfs"
+)
+
+// treeProcess is a process in a process tree fixture
+type treeProcess struct {
+	ppid  int
+	ticks int
+	start int
+	rss   int
+	read  int
+}
+
+// writeTree writes the stat and io files of the processes in a tree, and their
+// children files if children is true
+func writeTree(t *testing.T, fs procfs.FS, processes map[int]treeProcess,
+	children bool) {
+	t.Helper()
+	var childPids = make(map[int][]string)
+	for pid, process := range processes {
+		writeFile(t, fs.Path(fmt.Sprint(pid), "stat"), fmt.Sprintf(
+			"%d (worker) S %d %d %d 0 -1 0 0 0 0 0 %d 0 0 0 20 0 1 0 %d 1000000 %d\n",
+			pid, process.ppid, pid, pid, process.ticks, process.start, process.rss))
+		writeFile(t, fs.Path(fmt.Sprint(pid), "io"), fmt.Sprintf(
+			"rchar: 0\nwchar: 0\nsyscr: 0\nsyscw: 0\nread_bytes: %d\n"+
+				"write_bytes: 0\ncancelled_write_bytes: 0\n", process.read))
+		childPids[process.ppid] = append(childPids[process.ppid], fmt.Sprint(pid))
+	}
+
+	if children {
+		for pid := range processes {
+			var names = childPids[pid]
+			sort.Strings(names)
+			var content = ""
+			for _, name := range names {
+				content += name + " "
+			}
+			writeFile(t, fs.Path(fmt.Sprint(pid), "task", fmt.Sprint(pid),
+				"children"), content)
+		}
+	}
+}
+
+// rewindTree makes the last observation of a watch 10 seconds ago
+func rewindTree(watch *ProcessTreeWatch) {
+	for pid, sample := range watch.samples {
+		sample.observedTime = sample.observedTime.Add(-10 * time.Second)
+		watch.samples[pid] = sample
+	}
+	watch.lastObservation = watch.lastObservation.Add(-10 * time.Second)
+}
+
+func TestProcessTree(t *testing.T) {
+	var processes = map[int]treeProcess{
+		1:  {ppid: 0},
+		10: {ppid: 1},
+		20: {ppid: 10},
+		21: {ppid: 10},
+		30: {ppid: 20},
+		40: {ppid: 1},
+	}
+
+	for _, children := range []bool{true, false} {
+		var fs = procfs.NewFS(t.TempDir())
+		writeTree(t, fs, processes, children)
+
+		var tree = processTree(fs, 10)
+		if !reflect.DeepEqual(tree, []int{10, 20, 21, 30}) {
+			t.Errorf("children=%v: expected the tree below 10, got %v", children,
+				tree)
+		}
+	}
+}
+
+func TestProcessTreeWatch(t *testing.T) {
+	var fs = procfs.NewFS(t.TempDir())
+	var processes = map[int]treeProcess{
+		10: {ppid: 1, ticks: 100, start: 500, rss: 100, read: 1000},
+		20: {ppid: 10, ticks: 300, start: 600, rss: 50},
+		21: {ppid: 10, ticks: 0, start: 700, rss: 50},
+	}
+	writeTree(t, fs, processes, true)
+
+	var watch = NewProcessTreeWatch(fs, 10, ProcessTreeThresholds{
+		CPU:   12,
+		Count: 3,
+	})
+	if watch == nil {
+		t.Fatal("failed to create watch")
+	}
+	var tick, pageSize = float64(watch.sysClockTick), int64(watch.pageSize)
+
+	var observe = func() map[string]interface{} {
+		t.Helper()
+		rewindTree(watch)
+		var event, err = watch.ObserveContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event == nil {
+			return nil
+		}
+		return event.Data
+	}
+
+	// Below the thresholds is not reported
+	processes[10] = treeProcess{ppid: 1, ticks: 200, start: 500, rss: 100,
+		read: 11000}
+	writeTree(t, fs, processes, true)
+	if data := observe(); data != nil {
+		t.Fatalf("expected no event below the thresholds, got %v", data)
+	}
+
+	// The process at 20 has been replaced by another one with the same pid
+	// that started since the last observation, and a new process has started
+	processes[20] = treeProcess{ppid: 10, ticks: 50, start: 900, rss: 50}
+	processes[22] = treeProcess{ppid: 10, ticks: 100, start: 950, rss: 100}
+	writeTree(t, fs, processes, true)
+
+	var data = observe()
+	if data == nil {
+		t.Fatal("expected the thresholds to be exceeded")
+	}
+
+	var expectedCPU = map[int]float64{
+		10: 0,
+		20: 50 / tick / 10 * 100,
+		21: 0,
+		22: 100 / tick / 10 * 100,
+	}
+	var members = data[TreeProcesses].([]ProcessTreeMember)
+	if len(members) != len(expectedCPU) {
+		t.Fatalf("expected %d processes, got %v", len(expectedCPU), members)
+	}
+	for _, member := range members {
+		if math.Abs(member.CPU-expectedCPU[member.PID]) > 0.1 {
+			t.Errorf("expected process %d to use %f CPU, got %f", member.PID,
+				expectedCPU[member.PID], member.CPU)
+		}
+		if member.PPID != processes[member.PID].ppid {
+			t.Errorf("expected process %d to have parent %d, got %d", member.PID,
+				processes[member.PID].ppid, member.PPID)
+		}
+	}
+
+	if cpu := data[TreeCPU].(float64); math.Abs(cpu-150/tick/10*100) > 0.1 {
+		t.Errorf("expected the tree to use %f CPU, got %f", 150/tick/10*100, cpu)
+	}
+	if data[TreeRSS] != 300*pageSize || data[TreeCount] != 4 || data[TreePID] != 10 {
+		t.Errorf("expected 4 processes of %d bytes in tree 10, got %v", 300*pageSize,
+			data)
+	}
+	if exceeded := data[TreeExceeded]; !reflect.DeepEqual(exceeded,
+		[]string{TreeCount, TreeCPU}) {
+		t.Errorf("expected the count and CPU to be exceeded, got %v", exceeded)
+	}
+}
+
+func TestProcessTreeWatchIO(t *testing.T) {
+	var fs = procfs.NewFS(t.TempDir())
+	var processes = map[int]treeProcess{
+		10: {ppid: 1, start: 500, read: 1000},
+	}
+	writeTree(t, fs, processes, false)
+
+	var watch = NewProcessTreeWatch(fs, 10, ProcessTreeThresholds{ReadsPerSec: 100})
+	processes[10] = treeProcess{ppid: 1, start: 500, read: 3000}
+	writeTree(t, fs, processes, false)
+
+	rewindTree(watch)
+	var event, err = watch.ObserveContext(context.Background())
+	if err != nil || event == nil {
+		t.Fatalf("expected the reads to exceed the threshold, got %v %v", event, err)
+	}
+	if reads := event.Get(TreeReadsPerSec).(float64); math.Abs(reads-200) > 1 {
+		t.Errorf("expected 200 bytes read per second, got %f", reads)
+	}
+
+	if err = os.RemoveAll(fs.Path("10")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error when the process has gone")
+	}
+}
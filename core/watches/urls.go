@@ -1,6 +1,9 @@
 package watches
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -8,14 +11,51 @@ import (
 )
 
 var lastModifiedHeader = "Last-Modified"
+var etagHeader = "ETag"
+
+// URLName is a key in WatchEvent for the URL being watched
+var URLName = "url.name"
 
 // URLModifiedTime is a key in WatchEvent for when a URL was last modified
 var URLModifiedTime = "url.modifiedtime"
 
-// URLModifiedWatch is a Watch that observes when a URL is modified
+// URLETag is a key in WatchEvent for the ETag of a URL
+var URLETag = "url.etag"
+
+// URLValidators is a key in WatchEvent for the validators (etag and/or
+// last-modified) that changed when a URL was modified
+var URLValidators = "url.validators"
+
+// ValidatorETag is in URLValidators when the ETag of a URL changed
+var ValidatorETag = "etag"
+
+// ValidatorLastModified is in URLValidators when the Last-Modified time of a
+// URL changed
+var ValidatorLastModified = "last-modified"
+
+// URLDigest is a key in WatchEvent for the SHA-256 digest of a URL's content
+var URLDigest = "url.digest"
+
+// URLPreviousDigest is a key in WatchEvent for the SHA-256 digest of a URL's
+// previous content
+var URLPreviousDigest = "url.previousdigest"
+
+// URLDiff is a key in WatchEvent for a unified diff of a URL's text content
+var URLDiff = "url.diff"
+
+// ErrNoModifiedTime is returned when a URL reports neither a modified time
+// nor an ETag
+var ErrNoModifiedTime = errors.New("no " + lastModifiedHeader + " or " +
+	etagHeader + " header")
+
+// URLModifiedWatch is a Watch that observes when a URL is modified, using its
+// ETag and Last-Modified time
 type URLModifiedWatch struct {
 	url          string
 	lastModified time.Time
+	etag         string
+	useGet       bool
+	hash         *contentHash
 }
 
 // NewURLModifiedWatch creates a new URLModifiedWatch for the provided url
@@ -28,34 +68,169 @@ func NewURLModifiedWatch(url string) *URLModifiedWatch {
 	return watch
 }
 
+// NewURLContentWatch creates a new URLModifiedWatch that observes when the
+// content of a URL changes, using a SHA-256 digest of up to maxBytes of the
+// response body (or all of it if maxBytes <= 0). If diff is true, events for
+// text content include a unified diff of the change
+func NewURLContentWatch(url string, maxBytes int64,
+	diff bool) *URLModifiedWatch {
+	watch := new(URLModifiedWatch)
+	watch.url = url
+	watch.hash = newContentHash(maxBytes, diff)
+
+	watch.Observe()
+
+	return watch
+}
+
 // Observe whether a URL has changed since the last time it was observed
 func (watch *URLModifiedWatch) Observe() *core.WatchEvent {
-	resp, err := http.Head(watch.url)
+	return observe(watch.url, watch)
+}
+
+// ObserveContext observes whether a URL has changed since the last time it was
+// observed, returning an error if the URL could not be checked
+func (watch *URLModifiedWatch) ObserveContext(ctx context.Context) (*core.WatchEvent, error) {
+	if watch.hash != nil {
+		return watch.observeContent(ctx)
+	}
 
+	var method = http.MethodHead
+	if watch.useGet {
+		method = http.MethodGet
+	}
+
+	resp, err := watch.conditionalRequest(ctx, method)
 	if err != nil {
-		log.Debug("Failed to check url", watch.url, "error", err)
-		return nil
+		return nil, core.NewWatchError(watch.url, err)
 	}
 
+	// Fall back to conditional GETs if HEAD is not allowed
+	if method == http.MethodHead &&
+		(resp.StatusCode == http.StatusMethodNotAllowed ||
+			resp.StatusCode == http.StatusNotImplemented) {
+		log.Debug("HEAD not allowed for %s, using GET", watch.url)
+		watch.useGet = true
+
+		resp, err = watch.conditionalRequest(ctx, http.MethodGet)
+		if err != nil {
+			return nil, core.NewWatchError(watch.url, err)
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode >= 400 {
+		return nil, core.NewWatchError(watch.url,
+			fmt.Errorf("unexpected status %s", resp.Status))
+	}
+
+	var etag = resp.Header.Get(etagHeader)
 	var modTimeStr = resp.Header.Get(lastModifiedHeader)
 
-	if len(modTimeStr) == 0 {
-		log.Debug("No modified time for %s cannot observe", watch.url)
-		return nil
+	if len(modTimeStr) == 0 && len(etag) == 0 {
+		return nil, core.NewWatchError(watch.url, ErrNoModifiedTime)
+	}
+
+	var validators []string
+	var data = map[string]interface{}{
+		URLName: watch.url,
+	}
+
+	if len(etag) > 0 {
+		data[URLETag] = etag
+		if etag != watch.etag {
+			watch.etag = etag
+			validators = append(validators, ValidatorETag)
+		}
+	}
+
+	if len(modTimeStr) > 0 {
+		var modTime, parseErr = http.ParseTime(modTimeStr)
+		if parseErr != nil {
+			return nil, core.NewWatchError(watch.url, parseErr)
+		}
+
+		data[URLModifiedTime] = modTime
+		if modTime != watch.lastModified {
+			watch.lastModified = modTime
+			validators = append(validators, ValidatorLastModified)
+		}
+	}
+
+	if len(validators) > 0 {
+		data[URLValidators] = validators
+		return core.NewWatchEvent(data), nil
+	}
+
+	return nil, nil
+}
+
+// conditionalRequest makes a request for the URL that will get a 304 Not
+// Modified response if the URL has not changed since it was last observed
+func (watch *URLModifiedWatch) conditionalRequest(ctx context.Context,
+	method string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, watch.url, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	var modTime, parseErr = http.ParseTime(modTimeStr)
-	if parseErr != nil {
-		log.Debug("Failed to parse mod time", modTimeStr, "error", parseErr)
-		return nil
+	if len(watch.etag) > 0 {
+		req.Header.Set("If-None-Match", watch.etag)
+	}
+	if !watch.lastModified.IsZero() {
+		req.Header.Set("If-Modified-Since",
+			watch.lastModified.UTC().Format(http.TimeFormat))
 	}
 
-	if modTime != watch.lastModified {
-		watch.lastModified = modTime
-		return core.NewWatchEvent(map[string]interface{}{
-			URLModifiedTime: modTime,
-		})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Only the headers are needed
+	resp.Body.Close()
+	return resp, nil
+}
+
+// observeContent observes whether the content of the URL has changed
+func (watch *URLModifiedWatch) observeContent(ctx context.Context) (*core.WatchEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, watch.url, nil)
+	if err != nil {
+		return nil, core.NewWatchError(watch.url, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, core.NewWatchError(watch.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, core.NewWatchError(watch.url,
+			fmt.Errorf("unexpected status %s", resp.Status))
+	}
+
+	change, err := watch.hash.update(watch.url, resp.Body)
+	if err != nil {
+		return nil, core.NewWatchError(watch.url, err)
+	}
+
+	if change == nil {
+		return nil, nil
+	}
+
+	var data = map[string]interface{}{
+		URLName:           watch.url,
+		URLDigest:         change.digest,
+		URLPreviousDigest: change.previousDigest,
+	}
+	if len(change.diff) > 0 {
+		data[URLDiff] = change.diff
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get(lastModifiedHeader)); err == nil {
+		data[URLModifiedTime] = modTime
+	}
+	return core.NewWatchEvent(data), nil
 }
@@ -0,0 +1,226 @@
+package watches
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// contentServer serves content that can be changed between requests
+type contentServer struct {
+	sync.Mutex
+	content string
+}
+
+func (server *contentServer) set(content string) {
+	server.Lock()
+	defer server.Unlock()
+	server.content = content
+}
+
+func (server *contentServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.Lock()
+	defer server.Unlock()
+	fmt.Fprint(w, server.content)
+}
+
+func TestURLContentWatch(t *testing.T) {
+	var content = &contentServer{content: "one\ntwo\n"}
+	var server = httptest.NewServer(content)
+	defer server.Close()
+
+	// The first content is observed when the watch is created
+	var watch = NewURLContentWatch(server.URL, 0, true)
+	if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+		t.Fatalf("expected no change, got %v %v", event, err)
+	}
+
+	content.set("one\ntwo")
+	var event, err = watch.ObserveContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event == nil {
+		t.Fatal("expected a change when only the trailing newline changed")
+	}
+	if event.Get(URLName) != server.URL {
+		t.Errorf("expected the url name %s, got %v", server.URL, event.Get(URLName))
+	}
+	var expected = "--- " + server.URL + "\n+++ " + server.URL + "\n" +
+		"@@ -1,2 +1,2 @@\n one\n-two\n+two\n\\ No newline at end of file\n"
+	if event.Get(URLDiff) != expected {
+		t.Errorf("expected diff\n%s\ngot\n%v", expected, event.Get(URLDiff))
+	}
+	if event.Get(URLDigest) == event.Get(URLPreviousDigest) {
+		t.Error("expected the digest to change")
+	}
+}
+
+// validatorServer serves a resource with an ETag and Last-Modified time,
+// answering conditional requests with 304 Not Modified
+type validatorServer struct {
+	sync.Mutex
+	etag         string
+	lastModified time.Time
+	headAllowed  int
+	requests     []*http.Request
+}
+
+func (server *validatorServer) set(etag string, lastModified time.Time) {
+	server.Lock()
+	defer server.Unlock()
+	server.etag = etag
+	server.lastModified = lastModified
+}
+
+// last returns the last request made, and how many were made
+func (server *validatorServer) last() (*http.Request, int) {
+	server.Lock()
+	defer server.Unlock()
+	if len(server.requests) == 0 {
+		return nil, 0
+	}
+	return server.requests[len(server.requests)-1], len(server.requests)
+}
+
+func (server *validatorServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.Lock()
+	defer server.Unlock()
+	server.requests = append(server.requests, r)
+
+	if r.Method == http.MethodHead && server.headAllowed != 0 {
+		w.WriteHeader(server.headAllowed)
+		return
+	}
+
+	if len(server.etag) > 0 {
+		w.Header().Set(etagHeader, server.etag)
+		if r.Header.Get("If-None-Match") == server.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if !server.lastModified.IsZero() {
+		w.Header().Set(lastModifiedHeader,
+			server.lastModified.UTC().Format(http.TimeFormat))
+		var since, err = http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if len(server.etag) == 0 && err == nil &&
+			!server.lastModified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	fmt.Fprint(w, "content")
+}
+
+func TestURLModifiedWatchETag(t *testing.T) {
+	var validators = &validatorServer{etag: `"v1"`}
+	var server = httptest.NewServer(validators)
+	defer server.Close()
+
+	var watch = NewURLModifiedWatch(server.URL)
+	if watch.etag != `"v1"` {
+		t.Fatalf("expected the first ETag to be observed, got %s", watch.etag)
+	}
+
+	// The ETag is sent, so the server can say nothing has changed
+	if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+		t.Fatalf("expected no change, got %v %v", event, err)
+	}
+	var req, _ = validators.last()
+	if req.Method != http.MethodHead || req.Header.Get("If-None-Match") != `"v1"` {
+		t.Errorf("expected a conditional HEAD, got %s %v", req.Method, req.Header)
+	}
+
+	validators.set(`"v2"`, time.Time{})
+	var event, err = watch.ObserveContext(context.Background())
+	if err != nil || event == nil {
+		t.Fatalf("expected a change, got %v %v", event, err)
+	}
+	if event.Get(URLName) != server.URL || event.Get(URLETag) != `"v2"` {
+		t.Errorf("expected the new ETag for %s, got %v", server.URL, event.Data)
+	}
+	if changed, _ := event.Get(URLValidators).([]string); !reflect.DeepEqual(changed,
+		[]string{ValidatorETag}) {
+		t.Errorf("expected the ETag to have changed, got %v", changed)
+	}
+}
+
+func TestURLModifiedWatchLastModified(t *testing.T) {
+	var modTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var validators = &validatorServer{lastModified: modTime}
+	var server = httptest.NewServer(validators)
+	defer server.Close()
+
+	var watch = NewURLModifiedWatch(server.URL)
+	if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+		t.Fatalf("expected no change, got %v %v", event, err)
+	}
+	var req, _ = validators.last()
+	if req.Header.Get("If-Modified-Since") != modTime.Format(http.TimeFormat) {
+		t.Errorf("expected If-Modified-Since to be sent, got %v", req.Header)
+	}
+
+	validators.set("", modTime.Add(time.Hour))
+	var event, err = watch.ObserveContext(context.Background())
+	if err != nil || event == nil {
+		t.Fatalf("expected a change, got %v %v", event, err)
+	}
+	if event.Get(URLModifiedTime) != modTime.Add(time.Hour) {
+		t.Errorf("expected the new modified time, got %v", event.Get(URLModifiedTime))
+	}
+	if changed, _ := event.Get(URLValidators).([]string); !reflect.DeepEqual(changed,
+		[]string{ValidatorLastModified}) {
+		t.Errorf("expected the modified time to have changed, got %v", changed)
+	}
+}
+
+func TestURLModifiedWatchHeadFallback(t *testing.T) {
+	for _, status := range []int{http.StatusMethodNotAllowed,
+		http.StatusNotImplemented} {
+		var validators = &validatorServer{etag: `"v1"`, headAllowed: status}
+		var server = httptest.NewServer(validators)
+
+		var watch = NewURLModifiedWatch(server.URL)
+		if !watch.useGet || watch.etag != `"v1"` {
+			t.Errorf("%d: expected GET to be used after HEAD failed", status)
+		}
+
+		// Only GETs are made once HEAD has failed
+		if event, err := watch.ObserveContext(context.Background()); event != nil || err != nil {
+			t.Errorf("%d: expected no change, got %v %v", status, event, err)
+		}
+		var req, requests = validators.last()
+		if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != `"v1"` {
+			t.Errorf("%d: expected a conditional GET, got %s %v", status,
+				req.Method, req.Header)
+		}
+		if requests != 3 {
+			t.Errorf("%d: expected HEAD, GET and GET, got %d requests", status,
+				requests)
+		}
+		server.Close()
+	}
+}
+
+func TestURLModifiedWatchErrors(t *testing.T) {
+	var server = httptest.NewServer(&validatorServer{})
+	defer server.Close()
+
+	var watch = NewURLModifiedWatch(server.URL)
+	if _, err := watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error without an ETag or Last-Modified time")
+	}
+
+	var missing = httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	watch = NewURLModifiedWatch(missing.URL)
+	if _, err := watch.ObserveContext(context.Background()); err == nil {
+		t.Error("expected an error for a missing URL")
+	}
+}
@@ -0,0 +1,37 @@
+package watches
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/deanydean/clockwork/core"
+)
+
+// ErrProcessNotFound is returned when a watched process does not exist
+var ErrProcessNotFound = errors.New("process not found")
+
+// observe a ContextWatch without a deadline, logging any error and returning
+// the event if there was one
+func observe(name string, watch core.ContextWatch) *core.WatchEvent {
+	var event, err = watch.ObserveContext(context.Background())
+	if err != nil {
+		log.Debug("Failed to observe %s : %s", name, err)
+		return nil
+	}
+	return event
+}
+
+// exceededThresholds returns the keys of the values that are above their
+// thresholds, in order. Each key has a value and a threshold, and thresholds
+// that are 0 are not checked
+func exceededThresholds(values map[string][2]float64) []string {
+	var exceeded []string
+	for key, check := range values {
+		if check[1] > 0 && check[0] > check[1] {
+			exceeded = append(exceeded, key)
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded
+}
@@ -1,9 +1,14 @@
 package watchfiles
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/deanydean/clockwork/core"
 	"github.com/deanydean/clockwork/core/triggers"
@@ -16,7 +21,9 @@ var log = utils.GetLogger()
 
 // Watchfile containing watch information
 type Watchfile struct {
-	Watches    []core.Watch
+	Watches []core.Watch
+	// Schedules are when to observe each of the Watches, in the same order
+	Schedules  []watchers.Schedule
 	Triggers   []core.WatchTrigger
 	Properties map[string]string
 }
@@ -31,8 +38,12 @@ func GetWatcherFor(watchFileName *string) core.Watcher {
 		return nil
 	}
 
-	// Create a watcher for the watchfile
-	return watchers.NewWatchMan(watchFile.Watches)
+	// Create a watcher for the watchfile, observing each watch on its schedule
+	var watchMan = watchers.NewWatchMan(nil)
+	for w := range watchFile.Watches {
+		watchMan.Add(watchFile.Watches[w], watchFile.Schedules[w])
+	}
+	return watchMan
 }
 
 // Load a Watchfile
@@ -59,9 +70,13 @@ func Load(watchfile *string) *Watchfile {
 			// Watch defined
 			log.Debug("Adding watch from lineNo=%d, line=%s", lineIdx, line)
 			var watch = getWatch(line)
+			var schedule, err = getSchedule(line)
 
-			if watch != nil {
+			if err != nil {
+				log.Warn("Invalid schedule on lineNo=%d : %s", lineIdx, err)
+			} else if watch != nil {
 				wf.Watches = append(wf.Watches, watch)
+				wf.Schedules = append(wf.Schedules, schedule)
 			}
 		} else if strings.HasPrefix(line, "TELL") {
 			// Trigger defined
@@ -96,7 +111,25 @@ func getWatch(watchLine string) core.Watch {
 	switch url.Scheme {
 	case "file":
 		{
-			return watches.NewFileModifiedWatch(url.Path)
+			return getFileWatch(url)
+		}
+	case "tail":
+		{
+			var query = url.Query()
+			var tail = watches.NewTailWatch(url.Path, query.Get("match"),
+				query.Get("state"))
+			if tail != nil {
+				return tail
+			}
+			return nil
+		}
+	case "cert":
+		{
+			return getCertWatch(url)
+		}
+	case "net":
+		{
+			return getNetWatch(url)
 		}
 	case "http":
 		{
@@ -108,6 +141,151 @@ func getWatch(watchLine string) core.Watch {
 	return nil
 }
 
+// getSchedule gets the schedule for a watch line. The schedule is set with
+// interval=, delay= and jitter= durations after the watch url, such as
+// "WATCH file:///etc/hosts interval=10s jitter=1s". Anything not set is taken
+// from the DefaultSchedule
+func getSchedule(watchLine string) (watchers.Schedule, error) {
+	var schedule = watchers.DefaultSchedule
+	var sections = strings.Fields(watchLine)
+
+	for i := 2; i < len(sections); i++ {
+		var section = sections[i]
+		var kv = strings.SplitN(section, "=", 2)
+		if len(kv) != 2 {
+			return schedule, fmt.Errorf("invalid schedule parameter %s", section)
+		}
+
+		var value, err = time.ParseDuration(kv[1])
+		if err != nil {
+			return schedule, err
+		}
+		if value < 0 {
+			return schedule, fmt.Errorf("negative %s %s", kv[0], kv[1])
+		}
+
+		switch kv[0] {
+		case "interval":
+			schedule.Interval = value
+		case "delay":
+			schedule.Delay = value
+		case "jitter":
+			schedule.Jitter = value
+		default:
+			return schedule, fmt.Errorf("unknown schedule parameter %s", kv[0])
+		}
+	}
+
+	return schedule, nil
+}
+
+// getFileWatch gets a watch for a file url. A path with a glob in its last
+// element, or a directory, is watched as a directory. Directories are watched
+// recursively if the url has recursive=true
+func getFileWatch(fileURL *url.URL) core.Watch {
+	var path = fileURL.Path
+	var recursive = fileURL.Query().Get("recursive") == "true"
+
+	if utils.HasGlob(filepath.Base(path)) {
+		return watches.NewDirectoryWatch(filepath.Dir(path), recursive,
+			[]string{filepath.Base(path)}, nil)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return watches.NewDirectoryWatch(path, recursive, nil, nil)
+	}
+
+	return watches.NewFileModifiedWatch(path)
+}
+
+// getCertWatch gets a watch for a cert url, either cert://host:port or
+// cert:///path/to/file.pem. The days of warning before expiry can be set with
+// days=N, defaulting to 30
+func getCertWatch(certURL *url.URL) core.Watch {
+	var days = 30
+	if daysStr := certURL.Query().Get("days"); len(daysStr) > 0 {
+		var value, err = strconv.Atoi(daysStr)
+		if err != nil {
+			log.Warn("Invalid days=%s for %s", daysStr, certURL)
+			return nil
+		}
+		days = value
+	}
+
+	var target = certURL.Host
+	if len(target) == 0 {
+		target = "file://" + certURL.Path
+	}
+
+	var watch = watches.NewCertExpiryWatch(target, days)
+	if watch == nil {
+		return nil
+	}
+	return watch
+}
+
+// getNetWatch gets a watch for a net url, either net://<interface> for a live
+// capture or net:///path/to/file.pcap to read a capture file. The layer,
+// filter, snaplen, promisc, timeout, buffer, realtime, pps and bps query
+// parameters configure the watch
+func getNetWatch(netURL *url.URL) core.Watch {
+	var query = netURL.Query()
+
+	var layer = query.Get("layer")
+	if len(layer) == 0 {
+		layer = "IPv4"
+	}
+
+	var options = watches.DefaultNetCaptureOptions()
+	options.Filter = query.Get("filter")
+
+	var err error
+	if value := query.Get("snaplen"); len(value) > 0 {
+		var snapLen int
+		snapLen, err = strconv.Atoi(value)
+		options.SnapLen = int32(snapLen)
+	}
+	if value := query.Get("promisc"); len(value) > 0 && err == nil {
+		options.Promiscuous, err = strconv.ParseBool(value)
+	}
+	if value := query.Get("timeout"); len(value) > 0 && err == nil {
+		options.Timeout, err = time.ParseDuration(value)
+	}
+	if value := query.Get("buffer"); len(value) > 0 && err == nil {
+		options.BufferSize, err = strconv.Atoi(value)
+	}
+
+	var thresholds watches.NetThresholds
+	if value := query.Get("pps"); len(value) > 0 && err == nil {
+		thresholds.PacketsPerSec, err = strconv.ParseFloat(value, 64)
+	}
+	if value := query.Get("bps"); len(value) > 0 && err == nil {
+		thresholds.BytesPerSec, err = strconv.ParseFloat(value, 64)
+	}
+
+	if err != nil {
+		log.Warn("Invalid net watch %s : %s", netURL, err)
+		return nil
+	}
+
+	var watch *watches.NetWatch
+	if len(netURL.Host) > 0 {
+		var iface = netURL.Host
+		watch = watches.NewNetWatchWithOptions(&layer, &iface, options)
+	} else {
+		var file = netURL.Path
+		var realtime = query.Get("realtime") == "true"
+		watch = watches.NewNetFileWatch(&layer, &file, realtime, options.Filter)
+	}
+
+	if watch == nil {
+		return nil
+	}
+
+	watch.SetThresholds(thresholds)
+	return watch
+}
+
 func getTrigger(tellLine string) core.WatchTrigger {
 	// Split line by whitespace
 	var sections = strings.Split(tellLine, " ")
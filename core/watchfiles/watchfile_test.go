@@ -0,0 +1,169 @@
+package watchfiles
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deanydean/clockwork/core/watchers"
+	"github.com/deanydean/clockwork/core/watches"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+func TestLoadSchedules(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "watchfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var watched = filepath.Join(dir, "watched")
+	var watchFileName = filepath.Join(dir, "Watchfile")
+	var contents = "WATCH file://" + watched + "\n" +
+		"WATCH file://" + watched + " interval=10s delay=2s jitter=500ms\n" +
+		"WATCH file://" + watched + " interval=ten\n" +
+		"WATCH file://" + watched + " every=1s\n"
+	if err := ioutil.WriteFile(watchFileName, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var watchFile = Load(&watchFileName)
+	if watchFile == nil {
+		t.Fatal("failed to load watchfile")
+	}
+
+	var expected = []watchers.Schedule{
+		watchers.DefaultSchedule,
+		{Interval: 10 * time.Second, Delay: 2 * time.Second,
+			Jitter: 500 * time.Millisecond},
+	}
+	if len(watchFile.Watches) != len(expected) ||
+		len(watchFile.Schedules) != len(expected) {
+		t.Fatalf("expected %d watches, got %d watches and %d schedules",
+			len(expected), len(watchFile.Watches), len(watchFile.Schedules))
+	}
+	for i, schedule := range expected {
+		if watchFile.Schedules[i] != schedule {
+			t.Errorf("watch %d: expected schedule %+v, got %+v", i, schedule,
+				watchFile.Schedules[i])
+		}
+	}
+}
+
+func TestGetWatcherForUsesSchedules(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "watchfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var watchFileName = filepath.Join(dir, "Watchfile")
+	var contents = "WATCH file://" + filepath.Join(dir, "watched") +
+		" interval=1h delay=1h\n"
+	if err := ioutil.WriteFile(watchFileName, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var watchMan, ok = GetWatcherFor(&watchFileName).(*watchers.WatchMan)
+	if !ok {
+		t.Fatal("expected a WatchMan")
+	}
+
+	var runs = watchMan.NextRuns()
+	if len(runs) != 1 || time.Until(runs[0].Next) < 59*time.Minute {
+		t.Errorf("expected one watch delayed by an hour, got %+v", runs)
+	}
+}
+
+func TestFileWatchGlobs(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "watchfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var file = filepath.Join(dir, "watched")
+	if err = ioutil.WriteFile(file, []byte("watched"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var globURL, _ = url.Parse("file://" + dir + "/*.log?recursive=true")
+	var glob, ok = getFileWatch(globURL).(*watches.DirectoryWatch)
+	if !ok {
+		t.Fatal("expected a glob to be watched as a directory")
+	}
+	if _, ok = getFileWatch(&url.URL{Scheme: "file", Path: dir}).(*watches.DirectoryWatch); !ok {
+		t.Error("expected a directory to be watched as a directory")
+	}
+	if _, ok = getFileWatch(&url.URL{Scheme: "file", Path: file}).(*watches.FileModifiedWatch); !ok {
+		t.Error("expected a file to be watched as a file")
+	}
+
+	// Only files matching the glob are watched, in all the directories
+	for _, name := range []string{"a.log", "b.txt", "sub/c.log", "sub/d.txt"} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var event = glob.Observe()
+	if event == nil {
+		t.Fatal("expected the new files to be reported")
+	}
+	var expected = []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "sub", "c.log")}
+	if added := event.Get(watches.FilesAdded); !reflect.DeepEqual(added, expected) {
+		t.Errorf("expected %v to be added, got %v", expected, added)
+	}
+}
+
+func TestNetWatchOptions(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "watchfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var capture = filepath.Join(dir, "capture.pcap")
+	file, err := os.Create(capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pcapgo.NewWriter(file).WriteFileHeader(65536, layers.LinkTypeEthernet)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for query, valid := range map[string]bool{
+		"":                              true,
+		"layer=Ethernet&filter=udp":     true,
+		"realtime=true&pps=10&bps=1000": true,
+		"layer=NotALayer":               false,
+		"filter=not+a+filter+(":         false,
+		"pps=lots":                      false,
+		"snaplen=big":                   false,
+		"promisc=maybe&timeout=1s":      false,
+		"timeout=soon":                  false,
+	} {
+		var netURL = &url.URL{Scheme: "net", Path: capture, RawQuery: query}
+		var watch = getNetWatch(netURL)
+		if valid && watch == nil {
+			t.Errorf("expected %s to be watched", netURL)
+		} else if !valid && watch != nil {
+			t.Errorf("expected %s to not be watched", netURL)
+		}
+	}
+
+	var missing = &url.URL{Scheme: "net", Path: filepath.Join(dir, "missing.pcap")}
+	if getNetWatch(missing) != nil {
+		t.Error("expected a missing capture file to not be watched")
+	}
+}
@@ -13,6 +13,9 @@ import (
 func main() {
 	// Get cli flags
 	fileParam := flag.String("file", "", "The name of the file to watch")
+	hashParam := flag.Bool("hash", false, "Detect changes using a hash of the content")
+	maxBytesParam := flag.Int64("max-bytes", 0, "Maximum bytes of content to hash")
+	diffParam := flag.Bool("diff", false, "Show a diff of changed text content")
 	flag.Parse()
 
 	var fileName = *fileParam
@@ -21,13 +24,25 @@ func main() {
 		return
 	}
 
-	var modifiedWatch = watches.NewFileModifiedWatch(fileName)
+	var modifiedWatch *watches.FileModifiedWatch
+	if *hashParam {
+		modifiedWatch = watches.NewFileContentWatch(fileName, *maxBytesParam, *diffParam)
+	} else {
+		modifiedWatch = watches.NewFileModifiedWatch(fileName)
+	}
 	var watchMan = watchers.NewWatchMan([]core.Watch{modifiedWatch})
 
 	// Create the triggers
 	var modifiedTrigger = triggers.NewFuncTrigger(func(e *core.WatchEvent) {
 		fmt.Println(fileName, "has been modified at",
 			e.Get(watches.FileModTime))
+
+		if e.Get(watches.FileDigest) != nil {
+			fmt.Println("Content digest is now", e.Get(watches.FileDigest))
+		}
+		if e.Get(watches.FileDiff) != nil {
+			fmt.Print(e.Get(watches.FileDiff))
+		}
 	})
 
 	// Start watching
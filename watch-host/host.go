@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
+	"github.com/deanydean/clockwork/core/triggers"
+	"github.com/deanydean/clockwork/core/utils"
+	"github.com/deanydean/clockwork/core/watchers"
+	"github.com/deanydean/clockwork/core/watches"
+)
+
+var log = utils.GetLogger()
+
+func main() {
+	// Get cli flags
+	load1Flag := flag.Float64("load1", 0, "Report when the 1 minute load average is above this")
+	load5Flag := flag.Float64("load5", 0, "Report when the 5 minute load average is above this")
+	load15Flag := flag.Float64("load15", 0, "Report when the 15 minute load average is above this")
+	perCoreFlag := flag.Bool("per-core", false, "Divide the load averages by the number of cores")
+	memFlag := flag.Float64("mem", 0.9, "Report when this fraction of memory is not available")
+	swapFlag := flag.Float64("swap", 0.5, "Report when this fraction of swap is used")
+	swapInFlag := flag.Float64("swap-in", 0, "Report when pages swapped in per second is above this")
+	swapOutFlag := flag.Float64("swap-out", 0, "Report when pages swapped out per second is above this")
+	cpuFlag := flag.Float64("cpu", 90, "Report when the percentage of CPU used is above this")
+	coreFlag := flag.Float64("core", 0, "Report when the percentage used of any core is above this")
+	iowaitFlag := flag.Float64("iowait", 0, "Report when the percentage of CPU waiting for IO is above this")
+	stealFlag := flag.Float64("steal", 0, "Report when the percentage of CPU stolen by the hypervisor is above this")
+	cpuPressureFlag := flag.Float64("pressure-cpu", 0, "Report when the CPU pressure is above this percentage")
+	memoryPressureFlag := flag.Float64("pressure-memory", 0, "Report when the memory pressure is above this percentage")
+	ioPressureFlag := flag.Float64("pressure-io", 0, "Report when the IO pressure is above this percentage")
+	stateFlag := flag.String("state", "", "A file to save the boot id in, to report reboots between runs")
+	intervalFlag := flag.Duration("interval", time.Second, "How often to check the host")
+	debugFlag := flag.Bool("debug", false, "Is debug enabled?")
+	flag.Parse()
+
+	if *debugFlag {
+		utils.SetGlobalLogLevel(utils.LogDebug)
+	}
+
+	var hostWatches = []core.Watch{
+		watches.NewHostLoadWatch(procfs.Default, watches.HostLoadThresholds{
+			Load1:   *load1Flag,
+			Load5:   *load5Flag,
+			Load15:  *load15Flag,
+			PerCore: *perCoreFlag,
+		}),
+		watches.NewHostMemoryWatch(procfs.Default, watches.HostMemoryThresholds{
+			MemUsedRatio:  *memFlag,
+			SwapUsedRatio: *swapFlag,
+			SwapInPerSec:  *swapInFlag,
+			SwapOutPerSec: *swapOutFlag,
+		}),
+		watches.NewHostCPUWatch(procfs.Default, watches.HostCPUThresholds{
+			CPU:    *cpuFlag,
+			Core:   *coreFlag,
+			IOWait: *iowaitFlag,
+			Steal:  *stealFlag,
+		}),
+	}
+
+	// Reboots can only be seen between runs, so are only watched if the boot
+	// id is saved
+	if len(*stateFlag) > 0 {
+		hostWatches = append(hostWatches,
+			watches.NewHostRebootWatch(procfs.Default, *stateFlag))
+	}
+
+	// Pressure stall information isn't in every kernel, so only watch it if
+	// it's wanted
+	if *cpuPressureFlag > 0 || *memoryPressureFlag > 0 || *ioPressureFlag > 0 {
+		if !utils.PathExists("/proc/pressure") {
+			log.Error("No pressure stall information in this kernel")
+			os.Exit(1)
+		}
+		hostWatches = append(hostWatches,
+			watches.NewHostPressureWatch(procfs.Default, watches.HostPressureThresholds{
+				CPU:    *cpuPressureFlag,
+				Memory: *memoryPressureFlag,
+				IO:     *ioPressureFlag,
+			}))
+	}
+
+	var watchMan = watchers.NewWatchMan(nil)
+	for _, watch := range hostWatches {
+		watchMan.Add(watch, watchers.Schedule{Interval: *intervalFlag})
+	}
+
+	var trigger = triggers.NewFuncErrorTrigger(printEvent, func(err error) {
+		log.Warn("Failed to watch host: %s", err)
+	})
+
+	fmt.Println("Watching host....")
+	watchMan.Watch(trigger)
+	select {}
+}
+
+// printEvent prints a host event
+func printEvent(e *core.WatchEvent) {
+	if bootID := e.Get(watches.HostBootID); bootID != nil {
+		fmt.Println("Host rebooted at", e.Get(watches.HostBootTime), "boot id",
+			bootID, "previously", e.Get(watches.HostPreviousBootID))
+		return
+	}
+
+	var names = make([]string, 0, len(e.Data))
+	for name := range e.Data {
+		if name != watches.HostExceeded {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Host exceeded %s:", e.Get(watches.HostExceeded))
+	for _, name := range names {
+		switch value := e.Data[name].(type) {
+		case float64:
+			fmt.Fprintf(&summary, " %s=%.2f", name, value)
+		case map[int]float64:
+			var cores = make([]int, 0, len(value))
+			for number := range value {
+				cores = append(cores, number)
+			}
+			sort.Ints(cores)
+			fmt.Fprintf(&summary, " %s=[", name)
+			for i, number := range cores {
+				if i > 0 {
+					summary.WriteString(" ")
+				}
+				fmt.Fprintf(&summary, "%d:%.1f", number, value[number])
+			}
+			summary.WriteString("]")
+		default:
+			fmt.Fprintf(&summary, " %s=%v", name, value)
+		}
+	}
+	fmt.Println(summary.String())
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 
 	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
 	"github.com/deanydean/clockwork/core/triggers"
 	"github.com/deanydean/clockwork/core/utils"
 	"github.com/deanydean/clockwork/core/watchers"
@@ -17,6 +19,15 @@ var log = utils.GetLogger()
 func main() {
 	// Get cli flags
 	pidFlag := flag.Int("pid", -1, "The pid ")
+	nameFlag := flag.String("name", "", "Watch processes with this command name")
+	cmdlineFlag := flag.String("cmdline", "", "Watch processes with a command line matching this regex")
+	exeFlag := flag.String("exe", "", "Watch processes running this executable")
+	userFlag := flag.String("user", "", "Watch processes run by this user name or uid")
+	pidFileFlag := flag.String("pidfile", "", "Watch the process with the pid in this file")
+	cgroupFlag := flag.String("cgroup", "", "Watch processes in this cgroup")
+	readFlag := flag.Float64("read", 1048576, "Report when bytes read per second goes over this (0 to ignore)")
+	writeFlag := flag.Float64("write", 1048576, "Report when bytes written per second goes over this (0 to ignore)")
+	syscallsFlag := flag.Float64("syscalls", 0, "Report when read and write syscalls per second go over this (0 to ignore)")
 	debugFlag := flag.Bool("debug", false, "Is debug enabled?")
 	flag.Parse()
 
@@ -24,20 +35,73 @@ func main() {
 		utils.SetGlobalLogLevel(utils.LogDebug)
 	}
 
+	var thresholds = watches.ProcessIOThresholds{
+		ReadBytesPerSec:  *readFlag,
+		WriteBytesPerSec: *writeFlag,
+		SyscallsPerSec:   *syscallsFlag,
+	}
+
+	var selector = watches.ProcessSelector{
+		Comm:    *nameFlag,
+		Cmdline: *cmdlineFlag,
+		Exe:     *exeFlag,
+		User:    *userFlag,
+		PidFile: *pidFileFlag,
+		Cgroup:  *cgroupFlag,
+	}
+	if selector != (watches.ProcessSelector{}) {
+		var selectorWatch, err = watches.NewProcessSelectorWatch(procfs.Default, selector,
+			func(pid int) core.Watch {
+				if watch := watches.NewProcessHighIOWatchWithThresholds(procfs.Default, pid, thresholds); watch != nil {
+					return watch
+				}
+				return nil
+			})
+		if err != nil {
+			log.Error("Invalid process selector: %s", err)
+			os.Exit(1)
+		}
+		var trigger = triggers.NewFuncTrigger(func(e *core.WatchEvent) {
+			printEvent(e.Get(watches.ProcessPID).(int), e)
+		})
+		var watchMan = watchers.NewWatchMan([]core.Watch{selectorWatch})
+		watchMan.Watch(trigger)
+		select {}
+	}
+
 	var pid = *pidFlag
 	if pid <= 0 {
-		log.Error("Invalid pid %d\n", pid)
+		log.Error("Invalid pid %d", pid)
 		os.Exit(1)
-	} else if !utils.ProcessExists(pid) {
-		log.Error("No such process with pid %d\n", pid)
+	}
+
+	var ioWatch = watches.NewProcessHighIOWatchWithThresholds(procfs.Default, pid, thresholds)
+	if ioWatch == nil {
+		log.Error("Unable to watch io of process with pid %d", pid)
 		os.Exit(1)
 	}
 
-	var ioWatch = watches.NewProcessHighIOWatch(pid, 10)
-	var trigger = triggers.NewFuncTrigger(func(e *core.WatchEvent) {
-		fmt.Println("Process", pid, "has high io:")
+	var processGone = make(chan bool)
+	var trigger = triggers.NewFuncErrorTrigger(func(e *core.WatchEvent) {
+		printEvent(pid, e)
+	}, func(err error) {
+		if errors.Is(err, watches.ErrProcessNotFound) {
+			fmt.Println("Process", pid, "has gone")
+			processGone <- true
+			return
+		}
+		log.Warn("Failed to watch io: %s", err)
 	})
 	var watchMan = watchers.NewWatchMan([]core.Watch{ioWatch})
-	watchMan.Watch(trigger)
-	select {}
+	var cancelWatch = watchMan.Watch(trigger)
+
+	<-processGone
+	cancelWatch()
+}
+
+// printEvent prints a high io event for a process
+func printEvent(pid int, e *core.WatchEvent) {
+	fmt.Printf("Process %d has high io: read=%.0fB/s write=%.0fB/s syscalls=%.1f/s\n",
+		pid, e.Get(watches.IOReadsPerSec), e.Get(watches.IOWritesPerSec),
+		e.Get(watches.IOSyscallsPerSec))
 }
@@ -3,6 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/deanydean/clockwork/core"
 	"github.com/deanydean/clockwork/core/triggers"
@@ -18,6 +21,16 @@ func main() {
 	layerParam := flag.String("layer", "IPv4", "The network layer to watch")
 	ifaceParam := flag.String("interface", "eth0", "The network interface to watch")
 	debugParam := flag.Bool("debug", false, "Enable debug mode")
+	ppsParam := flag.Float64("pps", 0, "Only report when packets/sec is above this")
+	bpsParam := flag.Float64("bps", 0, "Only report when bytes/sec is above this")
+	topParam := flag.Int("top", 5, "The number of top talkers to report")
+	fileParam := flag.String("file", "", "Read packets from a pcap or pcapng file")
+	realtimeParam := flag.Bool("realtime", false, "Read the file at the speed it was captured")
+	filterParam := flag.String("filter", "", "A BPF filter for the packets to watch")
+	snapLenParam := flag.Int("snaplen", 65536, "The maximum bytes to capture from each packet")
+	promiscParam := flag.Bool("promisc", true, "Capture in promiscuous mode")
+	timeoutParam := flag.Duration("timeout", time.Second, "The capture read timeout")
+	bufferParam := flag.Int("buffer", 0, "The capture buffer size in bytes")
 	flag.Parse()
 
 	log.Info("Running network watch")
@@ -28,24 +41,84 @@ func main() {
 	}
 
 	// Create the watch
-	var modifiedWatch = watches.NewNetWatch(layerParam, ifaceParam)
+	var modifiedWatch *watches.NetWatch
+	if len(*fileParam) > 0 {
+		modifiedWatch = watches.NewNetFileWatch(layerParam, fileParam,
+			*realtimeParam, *filterParam)
+	} else {
+		modifiedWatch = watches.NewNetWatchWithOptions(layerParam, ifaceParam,
+			watches.NetCaptureOptions{
+				SnapLen:     int32(*snapLenParam),
+				Promiscuous: *promiscParam,
+				Timeout:     *timeoutParam,
+				BufferSize:  *bufferParam,
+				Filter:      *filterParam,
+			})
+	}
 	if modifiedWatch == nil {
 		fmt.Println("Failed to create net watch")
 		return
 	}
 
+	modifiedWatch.SetThresholds(watches.NetThresholds{
+		PacketsPerSec: *ppsParam,
+		BytesPerSec:   *bpsParam,
+		TopTalkers:    *topParam,
+	})
+
 	log.Debug("Created watch, now creating watchman")
 
 	var watchMan = watchers.NewWatchMan([]core.Watch{modifiedWatch})
 
 	// Create the triggers
+	var complete = make(chan bool)
 	var modifiedTrigger = triggers.NewFuncTrigger(func(e *core.WatchEvent) {
-		fmt.Printf("Some net event %s", e.Data)
+		printSummary(e)
+
+		if e.Get(watches.NetComplete) == true {
+			complete <- true
+		}
 	})
 
 	// Start watching
 	log.Info("Starting to watch....")
 	watchMan.Watch(modifiedTrigger)
 
-	select {}
+	<-complete
+	log.Info("Completed reading %s", *fileParam)
+}
+
+// printSummary prints the traffic summary in a net event
+func printSummary(e *core.WatchEvent) {
+	var summary strings.Builder
+
+	fmt.Fprintf(&summary, "%d packets, %d bytes", e.Get(watches.NetPackets),
+		e.Get(watches.NetBytes))
+	if interval := e.Get(watches.NetInterval); interval != nil {
+		fmt.Fprintf(&summary, " in %s (%.1f packets/s, %.1f bytes/s)", interval,
+			e.Get(watches.NetPacketsPerSec), e.Get(watches.NetBytesPerSec))
+	}
+	if exceeded := e.Get(watches.NetExceeded); exceeded != nil {
+		fmt.Fprintf(&summary, " exceeded %s", exceeded)
+	}
+	summary.WriteString("\n")
+
+	var protocols = e.Get(watches.NetProtocols).(map[string]int64)
+	var names = make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	summary.WriteString("  protocols:")
+	for _, name := range names {
+		fmt.Fprintf(&summary, " %s=%d", name, protocols[name])
+	}
+	summary.WriteString("\n")
+
+	for _, talker := range e.Get(watches.NetTopTalkers).([]watches.NetTalker) {
+		fmt.Fprintf(&summary, "  %-40s %8d packets %12d bytes\n",
+			talker.Endpoint, talker.Packets, talker.Bytes)
+	}
+
+	fmt.Print(summary.String())
 }
@@ -6,17 +6,48 @@ import (
 	"os"
 
 	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/procfs"
 	"github.com/deanydean/clockwork/core/triggers"
 	"github.com/deanydean/clockwork/core/utils"
 	"github.com/deanydean/clockwork/core/watchers"
 	"github.com/deanydean/clockwork/core/watches"
 )
 
+// cpuOptions are the options for the high CPU watches
+var cpuOptions watches.ProcessCPUOptions
+
 func main() {
 	// Get cli flags
 	pidFlag := flag.Int("pid", -1, "The pid ")
+	nameFlag := flag.String("name", "", "Watch processes with this command name")
+	cmdlineFlag := flag.String("cmdline", "", "Watch processes with a command line matching this regex")
+	exeFlag := flag.String("exe", "", "Watch processes running this executable")
+	userFlag := flag.String("user", "", "Watch processes run by this user name or uid")
+	pidFileFlag := flag.String("pidfile", "", "Watch the process with the pid in this file")
+	cgroupFlag := flag.String("cgroup", "", "Watch processes in this cgroup")
+	socketsFlag := flag.Bool("sockets", false, "Report the sockets the process holds")
+	childrenFlag := flag.Bool("children", false, "Include the CPU time of reaped children")
+	normalizedFlag := flag.Bool("normalized", false, "Compare CPU usage across all cores, not one")
 	flag.Parse()
 
+	cpuOptions = watches.ProcessCPUOptions{
+		IncludeChildren: *childrenFlag,
+		Normalized:      *normalizedFlag,
+	}
+
+	var selector = watches.ProcessSelector{
+		Comm:    *nameFlag,
+		Cmdline: *cmdlineFlag,
+		Exe:     *exeFlag,
+		User:    *userFlag,
+		PidFile: *pidFileFlag,
+		Cgroup:  *cgroupFlag,
+	}
+	if selector != (watches.ProcessSelector{}) {
+		watchSelected(selector, *socketsFlag)
+		return
+	}
+
 	var pid = *pidFlag
 	if pid <= 0 {
 		fmt.Fprintln(os.Stderr, "Invalid pid", pid)
@@ -29,15 +60,19 @@ func main() {
 	// Create process watches for CPU and Mem usage and one to see when the
 	// process ends
 	var deathWatch = watches.NewProcessDeathWatch(pid)
-	var highCPUWatch = watches.NewProcessHighCPUWatch(pid, 50)
+	var highCPUWatch = watches.NewProcessHighCPUWatchWithOptions(procfs.Default, pid, 50, cpuOptions)
 	var highMemWatch = watches.NewProcessHighMemWatch(pid, 500000000)
 
 	var watchMan = watchers.NewWatchMan([]core.Watch{highCPUWatch, highMemWatch})
 
 	// Create the triggers
 	var textOutputTrigger = triggers.NewFuncTrigger(func(e *core.WatchEvent) {
-		fmt.Println("Process", pid, e)
+		printEvent(pid, e)
 	})
+
+	if *socketsFlag {
+		watchMan.Add(watches.NewProcessSocketsWatch(procfs.Default, pid), watchers.DefaultSchedule)
+	}
 	// Start watching
 	fmt.Println("Watching pid", pid, "....")
 	var cancelWatch = watchMan.Watch(textOutputTrigger)
@@ -60,3 +95,62 @@ func main() {
 	// Need to wait for the death watcher....
 	<-processEnded
 }
+
+// watchSelected watches all the processes matching the selector until killed
+func watchSelected(selector watches.ProcessSelector, sockets bool) {
+	var selectorWatch = func(factory func(pid int) core.Watch) core.Watch {
+		var watch, err = watches.NewProcessSelectorWatch(procfs.Default, selector, factory)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid process selector:", err)
+			os.Exit(1)
+		}
+		return watch
+	}
+
+	var watchMan = watchers.NewWatchMan([]core.Watch{
+		selectorWatch(func(pid int) core.Watch {
+			if watch := watches.NewProcessHighCPUWatchWithOptions(procfs.Default, pid, 50, cpuOptions); watch != nil {
+				return watch
+			}
+			return nil
+		}),
+		selectorWatch(func(pid int) core.Watch {
+			if watch := watches.NewProcessHighMemWatch(pid, 500000000); watch != nil {
+				return watch
+			}
+			return nil
+		}),
+	})
+	if sockets {
+		watchMan.Add(selectorWatch(func(pid int) core.Watch {
+			return watches.NewProcessSocketsWatch(procfs.Default, pid)
+		}), watchers.DefaultSchedule)
+	}
+	watchMan.Watch(triggers.NewFuncTrigger(func(e *core.WatchEvent) {
+		printEvent(e.Get(watches.ProcessPID).(int), e)
+	}))
+
+	var deathWatchMan = watchers.NewWatchMan([]core.Watch{
+		selectorWatch(func(pid int) core.Watch {
+			return watches.NewProcessDeathWatch(pid)
+		}),
+	})
+	deathWatchMan.Watch(triggers.NewFuncTrigger(func(e *core.WatchEvent) {
+		fmt.Println("Process", e.Get(watches.ProcessPID), "died!")
+	}))
+
+	fmt.Println("Watching selected processes....")
+	select {}
+}
+
+// printEvent prints an event for a process
+func printEvent(pid int, e *core.WatchEvent) {
+	if sockets, ok := e.Get(watches.SocketList).([]watches.Socket); ok {
+		fmt.Println("Process", pid, "holds", len(sockets), "sockets:")
+		for _, socket := range sockets {
+			fmt.Println("  ", socket)
+		}
+		return
+	}
+	fmt.Println("Process", pid, e)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/utils"
+	"github.com/deanydean/clockwork/core/watchers"
+	"github.com/deanydean/clockwork/core/watches"
+)
+
+var log = utils.GetLogger()
+
+// LineTrigger prints each line as it is triggered, keeping them in order
+type LineTrigger struct {
+	showMatch bool
+}
+
+// OnEvent prints the line or match in the event
+func (trigger LineTrigger) OnEvent(e *core.WatchEvent) {
+	if trigger.showMatch {
+		fmt.Println(e.Get(watches.TailMatch))
+	} else {
+		fmt.Println(e.Get(watches.TailLine))
+	}
+}
+
+func main() {
+	// Get cli flags
+	fileParam := flag.String("file", "", "The file to tail")
+	matchParam := flag.String("match", "", "Only output lines matching this regex")
+	onlyMatchParam := flag.Bool("only-matching", false, "Output only the matching text")
+	stateParam := flag.String("state", "", "File to save the tail offset in")
+	debugFlag := flag.Bool("debug", false, "Is debug enabled?")
+	flag.Parse()
+
+	if *debugFlag {
+		utils.SetGlobalLogLevel(utils.LogDebug)
+	}
+
+	var fileName = *fileParam
+	if len(fileName) == 0 {
+		fmt.Println("Missing --file")
+		return
+	}
+
+	var tailWatch = watches.NewTailWatch(fileName, *matchParam, *stateParam)
+	if tailWatch == nil {
+		fmt.Fprintln(os.Stderr, "Cannot tail", fileName)
+		os.Exit(1)
+	}
+
+	var watchMan = watchers.NewWatchMan([]core.Watch{tailWatch})
+
+	// Start watching
+	log.Info("Tailing %s", fileName)
+	watchMan.Watch(LineTrigger{*onlyMatchParam && len(*matchParam) > 0})
+
+	select {}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/deanydean/clockwork/core"
+	"github.com/deanydean/clockwork/core/watches"
+)
+
+// captureOutput returns what is printed to stdout while running fn
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	var reader, writer, err = os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout = os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	writer.Close()
+	var output, _ = ioutil.ReadAll(reader)
+	return string(output)
+}
+
+func TestLineTrigger(t *testing.T) {
+	var events = []*core.WatchEvent{
+		core.NewWatchEvent(map[string]interface{}{
+			watches.TailLine:  "ERROR: 500 and WARN: 404",
+			watches.TailMatch: "ERROR: 500",
+		}),
+		core.NewWatchEvent(map[string]interface{}{
+			watches.TailLine:  "ERROR: 500 and WARN: 404",
+			watches.TailMatch: "WARN: 404",
+		}),
+	}
+
+	for _, test := range []struct {
+		showMatch bool
+		expected  string
+	}{
+		{false, "ERROR: 500 and WARN: 404\nERROR: 500 and WARN: 404\n"},
+		{true, "ERROR: 500\nWARN: 404\n"},
+	} {
+		var output = captureOutput(t, func() {
+			for _, event := range events {
+				LineTrigger{test.showMatch}.OnEvent(event)
+			}
+		})
+		if output != test.expected {
+			t.Errorf("showMatch=%v: expected %q, got %q", test.showMatch,
+				test.expected, output)
+		}
+	}
+}
@@ -13,6 +13,9 @@ import (
 func main() {
 	// Get cli flags
 	urlParam := flag.String("url", "", "The URL to watch")
+	hashParam := flag.Bool("hash", false, "Detect changes using a hash of the content")
+	maxBytesParam := flag.Int64("max-bytes", 0, "Maximum bytes of content to hash")
+	diffParam := flag.Bool("diff", false, "Show a diff of changed text content")
 	flag.Parse()
 	var url = *urlParam
 
@@ -22,7 +25,12 @@ func main() {
 	}
 
 	// Create the watch
-	var modifiedWatch = watches.NewURLModifiedWatch(url)
+	var modifiedWatch *watches.URLModifiedWatch
+	if *hashParam {
+		modifiedWatch = watches.NewURLContentWatch(url, *maxBytesParam, *diffParam)
+	} else {
+		modifiedWatch = watches.NewURLModifiedWatch(url)
+	}
 	if modifiedWatch == nil {
 		fmt.Println("Cannot watch url", url)
 		return
@@ -34,6 +42,16 @@ func main() {
 	var modifiedTrigger = triggers.NewFuncTrigger(func(e *core.WatchEvent) {
 		fmt.Println(url, "has been modified at",
 			e.Get(watches.URLModifiedTime))
+
+		if e.Get(watches.URLETag) != nil {
+			fmt.Println("ETag is now", e.Get(watches.URLETag))
+		}
+		if e.Get(watches.URLDigest) != nil {
+			fmt.Println("Content digest is now", e.Get(watches.URLDigest))
+		}
+		if e.Get(watches.URLDiff) != nil {
+			fmt.Print(e.Get(watches.URLDiff))
+		}
 	})
 
 	// Start watching